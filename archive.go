@@ -0,0 +1,319 @@
+package e5e
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ArchiveOptions control how an archive is built by [NewZipArchive] and [NewTarGzArchive]
+// and how it's unpacked by [OpenArchive].
+type ArchiveOptions struct {
+	// The filename of the resulting archive, e.g. "report.zip".
+	Name string
+
+	// The modification time that's set on every entry of the archive.
+	// If it's zero, the current time is used. Use [Context.Time] to use the time
+	// the event was triggered, which makes the archive reproducible for the same event.
+	ModTime time.Time
+
+	// The maximum total size of the files of a tar archive unpacked by [OpenArchive], so small, highly compressed
+	// archives can't exhaust the memory of the function. If it's zero, 256 MiB are used.
+	// Zip archives are not unpacked in advance, so the limit does not apply to them.
+	MaxUnpackedSize int64
+}
+
+const defaultMaxUnpackedSize = 256 << 20
+
+func (o ArchiveOptions) modTime() time.Time {
+	if o.ModTime.IsZero() {
+		return time.Now()
+	}
+	return o.ModTime
+}
+
+// NewZipArchive bundles the given files into a single zip archive.
+// Every file is stored under its [File.Name], files without a name are named "file-1", "file-2" etc.
+// The returned file can be used as the data of a [ResultDataTypeBinary] result.
+func NewZipArchive(files []File, opts ArchiveOptions) (*File, error) {
+	names, err := archiveEntryNames(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     names[i],
+			Method:   zip.Deflate,
+			Modified: opts.modTime(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("creating zip entry %q: %w", names[i], err)
+		}
+		if _, err := w.Write(f.content); err != nil {
+			return nil, fmt.Errorf("writing zip entry %q: %w", names[i], err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("finishing zip archive: %w", err)
+	}
+
+	archive := &File{Name: opts.Name, ContentType: "application/zip"}
+	_, _ = archive.Write(buf.Bytes())
+	return archive, nil
+}
+
+// NewTarGzArchive bundles the given files into a single gzip-compressed tar archive.
+// Every file is stored under its [File.Name], files without a name are named "file-1", "file-2" etc.
+// The returned file can be used as the data of a [ResultDataTypeBinary] result.
+func NewTarGzArchive(files []File, opts ArchiveOptions) (*File, error) {
+	names, err := archiveEntryNames(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for i, f := range files {
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     names[i],
+			Mode:     0o644,
+			Size:     int64(len(f.content)),
+			ModTime:  opts.modTime(),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("creating tar entry %q: %w", names[i], err)
+		}
+		if _, err := tw.Write(f.content); err != nil {
+			return nil, fmt.Errorf("writing tar entry %q: %w", names[i], err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("finishing tar archive: %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return nil, fmt.Errorf("finishing gzip stream: %w", err)
+	}
+
+	archive := &File{Name: opts.Name, ContentType: "application/gzip"}
+	_, _ = archive.Write(buf.Bytes())
+	return archive, nil
+}
+
+// archiveEntryNames returns the names under which the given files are stored inside an archive.
+// It returns an error if a name would escape the archive root or if it's used more than once.
+func archiveEntryNames(files []File) ([]string, error) {
+	names := make([]string, len(files))
+	seen := make(map[string]bool, len(files))
+	for i, f := range files {
+		name := f.Name
+		if name == "" {
+			name = fmt.Sprintf("file-%d", i+1)
+		}
+		name = strings.TrimPrefix(path.Clean(strings.ReplaceAll(name, "\\", "/")), "/")
+		if !fs.ValidPath(name) || name == "." {
+			return nil, fmt.Errorf("file name %q is not allowed inside an archive", f.Name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("file name %q is used more than once", name)
+		}
+		seen[name] = true
+		names[i] = name
+	}
+	return names, nil
+}
+
+// OpenArchive unpacks a zip or a gzip-compressed tar archive, e.g. one that got uploaded with
+// a [EventDataTypeBinary] event, and provides read access to its contents.
+//
+// The archive format is detected by the content of the file, [File.ContentType] is ignored.
+// If the format is not supported, an [UnsupportedArchiveError] is returned. If the files of a tar archive exceed
+// [ArchiveOptions.MaxUnpackedSize], an [ArchiveTooLargeError] is returned.
+func OpenArchive(f File, opts ArchiveOptions) (fs.FS, error) {
+	switch {
+	case bytes.HasPrefix(f.content, []byte("PK\x03\x04")), bytes.HasPrefix(f.content, []byte("PK\x05\x06")):
+		zr, err := zip.NewReader(bytes.NewReader(f.content), int64(len(f.content)))
+		if err != nil {
+			return nil, fmt.Errorf("reading zip archive: %w", err)
+		}
+		return zr, nil
+	case bytes.HasPrefix(f.content, []byte("\x1f\x8b")):
+		gr, err := gzip.NewReader(bytes.NewReader(f.content))
+		if err != nil {
+			return nil, fmt.Errorf("reading gzip stream: %w", err)
+		}
+		defer gr.Close()
+		maxSize := opts.MaxUnpackedSize
+		if maxSize == 0 {
+			maxSize = defaultMaxUnpackedSize
+		}
+		return readTar(gr, maxSize)
+	default:
+		return nil, UnsupportedArchiveError{ContentType: f.ContentType}
+	}
+}
+
+// readTar reads all regular files and directories of a tar archive into memory,
+// as long as the files don't exceed maxSize bytes in total.
+func readTar(r io.Reader, maxSize int64) (fs.FS, error) {
+	fsys := memFS{".": {name: ".", mode: fs.ModeDir | 0o755}}
+	tr := tar.NewReader(r)
+	var size int64
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return fsys, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading tar archive: %w", err)
+		}
+
+		name := strings.TrimPrefix(path.Clean(header.Name), "/")
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("tar entry %q has an invalid path", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := fsys.mkdirAll(name, header.ModTime); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			if existing, ok := fsys[name]; ok && existing.mode.IsDir() {
+				return nil, fmt.Errorf("tar entry %q is a file, but a directory with the same path exists", header.Name)
+			}
+			data, err := io.ReadAll(io.LimitReader(tr, maxSize-size+1))
+			if err != nil {
+				return nil, fmt.Errorf("reading tar entry %q: %w", header.Name, err)
+			}
+			if size += int64(len(data)); size > maxSize {
+				return nil, ArchiveTooLargeError{MaxSize: maxSize}
+			}
+			if err := fsys.mkdirAll(path.Dir(name), header.ModTime); err != nil {
+				return nil, err
+			}
+			fsys[name] = &memEntry{
+				name:    path.Base(name),
+				data:    data,
+				mode:    fs.FileMode(header.Mode).Perm(),
+				modTime: header.ModTime,
+			}
+		default:
+			// Links, devices and the like do not make sense for uploaded archives, so they're skipped.
+		}
+	}
+}
+
+// memFS is a minimal, read-only, in-memory [fs.FS] keyed by the full path of every entry.
+type memFS map[string]*memEntry
+
+type memEntry struct {
+	name    string
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// mkdirAll creates the directory and all its parents that don't exist yet.
+// It returns an error if a file with the path of one of the directories exists.
+func (m memFS) mkdirAll(dir string, modTime time.Time) error {
+	for ; dir != "."; dir = path.Dir(dir) {
+		if existing, exists := m[dir]; exists {
+			if !existing.mode.IsDir() {
+				return fmt.Errorf("tar entry %q is a directory, but a file with the same path exists", dir)
+			}
+			return nil
+		}
+		m[dir] = &memEntry{name: path.Base(dir), mode: fs.ModeDir | 0o755, modTime: modTime}
+	}
+	return nil
+}
+
+// Open implements fs.FS.
+func (m memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	entry, ok := m[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if !entry.mode.IsDir() {
+		return &memFile{entry: entry, Reader: bytes.NewReader(entry.data)}, nil
+	}
+
+	var children []fs.DirEntry
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+	for p, child := range m {
+		if p != "." && strings.HasPrefix(p, prefix) && !strings.Contains(p[len(prefix):], "/") {
+			children = append(children, fs.FileInfoToDirEntry(child))
+		}
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Name() < children[j].Name() })
+	return &memDir{entry: entry, children: children}, nil
+}
+
+func (e *memEntry) Name() string       { return e.name }
+func (e *memEntry) Size() int64        { return int64(len(e.data)) }
+func (e *memEntry) Mode() fs.FileMode  { return e.mode }
+func (e *memEntry) ModTime() time.Time { return e.modTime }
+func (e *memEntry) IsDir() bool        { return e.mode.IsDir() }
+func (e *memEntry) Sys() any           { return nil }
+
+type memFile struct {
+	*bytes.Reader
+	entry *memEntry
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.entry, nil }
+func (f *memFile) Close() error               { return nil }
+
+type memDir struct {
+	entry    *memEntry
+	children []fs.DirEntry
+	offset   int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.entry, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.entry.name, Err: errors.New("is a directory")}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.children[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.children)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
+
+// compile-time check for certain interfaces
+var _ fs.FS = memFS{}
+var _ fs.ReadDirFile = &memDir{}
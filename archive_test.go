@@ -0,0 +1,182 @@
+package e5e_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestArchive(t *testing.T) {
+	t.Parallel()
+
+	modTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	newFile := func(name, content string) e5e.File {
+		f := e5e.File{Name: name}
+		_ = f.SetPlainText(content)
+		return f
+	}
+	files := []e5e.File{
+		newFile("report.txt", "Hello world!"),
+		newFile("nested/data.csv", "a,b\n1,2\n"),
+		newFile("", "unnamed"),
+	}
+
+	tests := []struct {
+		name        string
+		create      func([]e5e.File, e5e.ArchiveOptions) (*e5e.File, error)
+		contentType string
+	}{
+		{name: "zip", create: e5e.NewZipArchive, contentType: "application/zip"},
+		{name: "tar.gz", create: e5e.NewTarGzArchive, contentType: "application/gzip"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			archive, err := tt.create(files, e5e.ArchiveOptions{Name: "archive." + tt.name, ModTime: modTime})
+			if err != nil {
+				t.Fatalf("creating archive failed: %v", err)
+			}
+			Equal(t, "archive."+tt.name, archive.Name, "archive name does not match")
+			Equal(t, tt.contentType, archive.ContentType, "content type does not match")
+			Equal(t, int64(len(archive.Bytes())), archive.SizeInBytes, "archive size does not match")
+
+			fsys, err := e5e.OpenArchive(*archive, e5e.ArchiveOptions{})
+			if err != nil {
+				t.Fatalf("opening archive failed: %v", err)
+			}
+			if err := fstest.TestFS(fsys, "report.txt", "nested/data.csv", "file-3"); err != nil {
+				t.Fatalf("archive file system is invalid: %v", err)
+			}
+
+			content, err := fs.ReadFile(fsys, "nested/data.csv")
+			if err != nil {
+				t.Fatalf("reading archive entry failed: %v", err)
+			}
+			Equal(t, "a,b\n1,2\n", string(content), "entry content does not match")
+
+			info, err := fs.Stat(fsys, "report.txt")
+			if err != nil {
+				t.Fatalf("stat on archive entry failed: %v", err)
+			}
+			Equal(t, true, info.ModTime().Equal(modTime), "modification time does not match")
+		})
+	}
+
+	t.Run("invalid names are rejected", func(t *testing.T) {
+		t.Parallel()
+		for _, name := range []string{"../escape.txt", "/"} {
+			if _, err := e5e.NewZipArchive([]e5e.File{newFile(name, "x")}, e5e.ArchiveOptions{}); err == nil {
+				t.Errorf("expected an error for name %q, got none", name)
+			}
+		}
+	})
+	t.Run("duplicate names are rejected", func(t *testing.T) {
+		t.Parallel()
+		_, err := e5e.NewTarGzArchive([]e5e.File{newFile("a.txt", "1"), newFile("./a.txt", "2")}, e5e.ArchiveOptions{})
+		if err == nil {
+			t.Errorf("expected an error, got none")
+		}
+	})
+	t.Run("unknown formats are rejected", func(t *testing.T) {
+		t.Parallel()
+		_, err := e5e.OpenArchive(newFile("plain.txt", "not an archive"), e5e.ArchiveOptions{})
+		var archiveErr e5e.UnsupportedArchiveError
+		if !errors.As(err, &archiveErr) {
+			t.Fatalf("expected an UnsupportedArchiveError, got: %v", err)
+		}
+		Equal(t, "text/plain", archiveErr.ContentType, "content type does not match")
+	})
+	t.Run("large tar archives are rejected", func(t *testing.T) {
+		t.Parallel()
+		archive := newTarGz(t, &tar.Header{Name: "a.bin", Size: 600}, &tar.Header{Name: "b.bin", Size: 600})
+		_, err := e5e.OpenArchive(archive, e5e.ArchiveOptions{MaxUnpackedSize: 1000})
+		var sizeErr e5e.ArchiveTooLargeError
+		if !errors.As(err, &sizeErr) {
+			t.Fatalf("expected an ArchiveTooLargeError, got: %v", err)
+		}
+		Equal(t, int64(1000), sizeErr.MaxSize, "maximum size does not match")
+
+		if _, err := e5e.OpenArchive(archive, e5e.ArchiveOptions{MaxUnpackedSize: 1200}); err != nil {
+			t.Errorf("archive within the limit is rejected: %v", err)
+		}
+	})
+	t.Run("entries overwriting directories are rejected", func(t *testing.T) {
+		t.Parallel()
+		tests := map[string][]*tar.Header{
+			"root":                 {{Name: ".", Size: 1}},
+			"synthesized dir":      {{Name: "dir/a.txt", Size: 1}, {Name: "dir", Size: 1}},
+			"file as parent":       {{Name: "dir", Size: 1}, {Name: "dir/a.txt", Size: 1}},
+			"file as declared dir": {{Name: "dir", Size: 1}, {Name: "dir/", Typeflag: tar.TypeDir}},
+		}
+		for name, headers := range tests {
+			if _, err := e5e.OpenArchive(newTarGz(t, headers...), e5e.ArchiveOptions{}); err == nil {
+				t.Errorf("%s: expected an error, got none", name)
+			}
+		}
+
+		fsys, err := e5e.OpenArchive(newTarGz(t, &tar.Header{Name: "./", Typeflag: tar.TypeDir}, &tar.Header{Name: "./a.txt", Size: 1}), e5e.ArchiveOptions{})
+		if err != nil {
+			t.Fatalf("archive with a root directory entry is rejected: %v", err)
+		}
+		if err := fstest.TestFS(fsys, "a.txt"); err != nil {
+			t.Fatalf("archive file system is invalid: %v", err)
+		}
+	})
+}
+
+// newTarGz returns a gzip-compressed tar archive with the given entries.
+// Regular files are filled with zeros up to their size.
+func newTarGz(t *testing.T, headers ...*tar.Header) e5e.File {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, header := range headers {
+		if header.Typeflag == 0 {
+			header.Typeflag = tar.TypeReg
+		}
+		header.Mode = 0o644
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("writing tar header failed: %v", err)
+		}
+		if _, err := tw.Write(make([]byte, header.Size)); err != nil {
+			t.Fatalf("writing tar entry failed: %v", err)
+		}
+	}
+	if err := errors.Join(tw.Close(), gw.Close()); err != nil {
+		t.Fatalf("closing archive failed: %v", err)
+	}
+	f := e5e.File{Name: "archive.tar.gz", ContentType: "application/gzip"}
+	_, _ = f.Write(buf.Bytes())
+	return f
+}
+
+func TestContextTime(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		date     string
+		expected time.Time
+	}{
+		{date: "2022-08-04T14:15:53.885414", expected: time.Date(2022, 8, 4, 14, 15, 53, 885414000, time.UTC)},
+		{date: "2024-01-01T00:00:00Z", expected: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		actual, err := e5e.Context[any]{Date: tt.date}.Time()
+		if err != nil {
+			t.Fatalf("parsing %q failed: %v", tt.date, err)
+		}
+		Equal(t, true, actual.Equal(tt.expected), "time of "+tt.date+" does not match")
+	}
+
+	if _, err := (e5e.Context[any]{Date: "yesterday"}).Time(); err == nil {
+		t.Errorf("expected an error for an invalid date, got none")
+	}
+}
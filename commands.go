@@ -0,0 +1,104 @@
+package e5e
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runCommand runs the local development command given by the process arguments, if there is one.
+// It returns false if the arguments do not contain a command, so the runtime should be started instead.
+// Commands exit the process with a non-zero exit code on failures and return normally otherwise.
+func runCommand(ctx context.Context, args []string) bool {
+	// The "metadata" command is handled by [Start] before the runtime is configured.
+	switch command(args) {
+	case "serve":
+		addr := defaultDevServerAddr
+		if len(args) == 3 {
+			addr = args[2]
+		}
+		if err := ListenAndServe(ctx, addr); err != nil {
+			panic(err)
+		}
+	case "replay":
+		exitOnFailure(replayCommand(ctx, args[2]))
+	case "invoke":
+		exitOnFailure(invokeCommand(ctx, args[2:]))
+	default:
+		return false
+	}
+	return true
+}
+
+// command returns the name of the command given by the process arguments, or an empty string if there is none.
+// A command is only recognized with the number of arguments it takes, so entrypoints may be named like commands.
+// Arguments in the form e5e starts the binary with are never a command, see [isRuntimeInvocation].
+func command(args []string) string {
+	if len(args) < 2 || isRuntimeInvocation(args) {
+		return ""
+	}
+	switch name := args[1]; {
+	case name == "metadata" && len(args) == 2,
+		name == "serve" && len(args) <= 3,
+		name == "replay" && len(args) == 3,
+		name == "invoke":
+		return name
+	}
+	return ""
+}
+
+// isRuntimeInvocation returns true if the arguments have the shape e5e starts the binary with, see [parseArguments]:
+// a registered entrypoint, followed by the stdout sequence, the keepalive flag and the daemon sequence.
+func isRuntimeInvocation(args []string) bool {
+	if len(args) != 5 || args[3] != "0" && args[3] != "1" {
+		return false
+	}
+	_, registered := globalMux.handlers[args[1]]
+	return registered
+}
+
+// exitOnFailure exits the process with the given code, unless it's zero.
+// On success, the command returns normally, so deferred functions of the caller still run.
+func exitOnFailure(code int) {
+	if code != 0 {
+		os.Exit(code)
+	}
+}
+
+func exitWithUsage(usage string) {
+	_, _ = fmt.Fprintf(os.Stderr, "usage: %s %s\n", os.Args[0], usage)
+	os.Exit(2)
+}
+
+// replayCommand replays the given recording file, prints a report to [os.Stdout] and returns the exit code.
+func replayCommand(ctx context.Context, path string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "go-e5e: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+
+	results, err := Replay(ctx, f)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "go-e5e: %v\n", err)
+		return 1
+	}
+
+	failed := 0
+	for i, r := range results {
+		if r.Equal() {
+			_, _ = fmt.Fprintf(os.Stdout, "ok   %d %s %s\n", i+1, r.Recording.Entrypoint, r.Recording.Time.Format("2006-01-02T15:04:05Z07:00"))
+			continue
+		}
+		failed++
+		_, _ = fmt.Fprintf(os.Stdout, "FAIL %d %s %s\n    %s\n", i+1, r.Recording.Entrypoint, r.Recording.Time.Format("2006-01-02T15:04:05Z07:00"),
+			strings.Join(r.Differences, "\n    "))
+	}
+	_, _ = fmt.Fprintf(os.Stdout, "%d of %d replayed events differ\n", failed, len(results))
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,368 @@
+package e5e
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CompressionOptions configure the [Compression] middleware.
+type CompressionOptions struct {
+	// Results whose encoded data is smaller than MinSize bytes are not compressed,
+	// since the compression overhead outweighs the savings.
+	// If it's zero, 1024 bytes are used.
+	MinSize int
+
+	// The compression level passed to [gzip.NewWriterLevel] or [zlib.NewWriterLevel].
+	// If it's zero, the default compression level is used.
+	Level int
+
+	// Additional content types (or content type prefixes like "application/x-") that are not compressed
+	// in addition to the content types that are already compressed, like images, videos or archives.
+	SkipContentTypes []string
+
+	// Request bodies that are larger than MaxDecompressedSize bytes after decompressing them are rejected,
+	// so small, highly compressed bodies can't exhaust the memory of the function.
+	// If it's zero, 64 MiB are used.
+	MaxDecompressedSize int64
+}
+
+const (
+	defaultCompressionMinSize  = 1024
+	defaultMaxDecompressedSize = 64 << 20
+)
+
+// errDecompressedTooLarge is returned by decompress, if the decompressed data exceeds the maximum size.
+var errDecompressedTooLarge = errors.New("decompressed request body is too large")
+
+// incompressibleContentTypes contains prefixes of content types that are already compressed.
+var incompressibleContentTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zip",
+	"application/zstd",
+	"application/x-7z-compressed",
+	"application/x-bzip2",
+	"application/x-rar-compressed",
+	"application/x-xz",
+}
+
+// compressibleExceptions contains content types that match a prefix of incompressibleContentTypes,
+// but are text-based and compress well.
+var compressibleExceptions = []string{"image/svg+xml"}
+
+// Compression returns a middleware that transparently handles compressed request and response bodies.
+//
+// Incoming text and binary events are decompressed according to their Content-Encoding request header,
+// which is removed afterward. Unsupported encodings are answered with status 415, invalid bodies with status 400
+// and bodies exceeding [CompressionOptions.MaxDecompressedSize] with status 413, without invoking the handler.
+//
+// Outgoing results are compressed according to the Accept-Encoding request header, supporting "gzip" and "deflate".
+// Since the compressed data is binary, text and object results are converted to binary results with the
+// content type "text/plain" or "application/json" respectively. The Content-Encoding and Vary response headers
+// are set accordingly. Results that are too small, have an already compressed content type or already contain
+// a Content-Encoding header are left untouched.
+func Compression(opts CompressionOptions) Middleware {
+	if opts.MinSize == 0 {
+		opts.MinSize = defaultCompressionMinSize
+	}
+	if opts.Level == 0 {
+		opts.Level = gzip.DefaultCompression
+	}
+	if opts.MaxDecompressedSize == 0 {
+		opts.MaxDecompressedSize = defaultMaxDecompressedSize
+	}
+
+	return func(next HandlerFactory) HandlerFactory {
+		return HandlerFactoryFunc(func(ctx context.Context, payload []byte) (*Result, error) {
+			request, err := decodeRawRequest(payload)
+			if err != nil {
+				return nil, err
+			}
+
+			if encoding := request.Event.Header("Content-Encoding"); encoding != "" {
+				var res *Result
+				payload, res, err = decompressRequest(request, encoding, opts.MaxDecompressedSize)
+				if err != nil || res != nil {
+					return res, err
+				}
+			}
+
+			res, err := next.Execute(ctx, payload)
+			if err != nil || res == nil {
+				return res, err
+			}
+			return compressResult(res, request.Event.Header("Accept-Encoding"), opts)
+		})
+	}
+}
+
+// decompressRequest decodes the data of the given request and returns the re-encoded payload.
+// If the request cannot be decompressed, an error result is returned instead.
+func decompressRequest(request rawRequest, encoding string, maxSize int64) ([]byte, *Result, error) {
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+	if encoding == "identity" {
+		return reencodeRequest(request)
+	}
+	if encoding != "gzip" && encoding != "x-gzip" && encoding != "deflate" {
+		return nil, &Result{
+			Status:          415,
+			ResponseHeaders: map[string]string{"Accept-Encoding": "gzip, deflate"},
+			Type:            ResultDataTypeText,
+			Data:            fmt.Sprintf("unsupported content encoding %q", encoding),
+		}, nil
+	}
+
+	invalidBody := func(err error) ([]byte, *Result, error) {
+		if errors.Is(err, errDecompressedTooLarge) {
+			return nil, &Result{
+				Status: 413,
+				Type:   ResultDataTypeText,
+				Data:   fmt.Sprintf("decompressed request body exceeds %d bytes", maxSize),
+			}, nil
+		}
+		return nil, &Result{
+			Status: 400,
+			Type:   ResultDataTypeText,
+			Data:   fmt.Sprintf("decompressing request body failed: %v", err),
+		}, nil
+	}
+
+	switch request.Event.Type {
+	case EventDataTypeBinary:
+		var file File
+		if err := json.Unmarshal(request.Event.Data, &file); err != nil {
+			return nil, nil, fmt.Errorf("unmarshaling binary event data failed: %w", err)
+		}
+		content, err := decompress(encoding, file.content, maxSize)
+		if err != nil {
+			return invalidBody(err)
+		}
+		file.content = content
+		file.SizeInBytes = int64(len(content))
+		if request.Event.Data, err = json.Marshal(file); err != nil {
+			return nil, nil, err
+		}
+	case EventDataTypeText:
+		var text string
+		if err := json.Unmarshal(request.Event.Data, &text); err != nil {
+			return nil, nil, fmt.Errorf("unmarshaling text event data failed: %w", err)
+		}
+		content, err := decompress(encoding, []byte(text), maxSize)
+		if err != nil {
+			return invalidBody(err)
+		}
+		if request.Event.Data, err = json.Marshal(string(content)); err != nil {
+			return nil, nil, err
+		}
+	default:
+		// Objects and mixed data are already decoded by E5E, so there's nothing left to decompress.
+	}
+
+	request.Event.RequestHeaders = deleteHeader(cloneHeaders(request.Event.RequestHeaders), "Content-Encoding")
+	return reencodeRequest(request)
+}
+
+func reencodeRequest(request rawRequest) ([]byte, *Result, error) {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshalling decompressed request: %w", err)
+	}
+	return payload, nil, nil
+}
+
+// compressResult compresses the given result, if the client accepts it and it's worth it.
+func compressResult(res *Result, acceptEncoding string, opts CompressionOptions) (*Result, error) {
+	if headerValue(res.ResponseHeaders, "Content-Encoding") != "" {
+		return res, nil
+	}
+
+	var (
+		content     []byte
+		contentType string
+		file        File
+	)
+	switch res.Type {
+	case ResultDataTypeBinary:
+		switch f := res.Data.(type) {
+		case File:
+			file = f
+		case *File:
+			if f == nil {
+				return res, nil
+			}
+			file = *f
+		default:
+			return res, nil
+		}
+		content, contentType = file.content, file.ContentType
+	case ResultDataTypeText:
+		text, ok := res.Data.(string)
+		if !ok {
+			return res, nil
+		}
+		content, contentType = []byte(text), "text/plain"
+		file = File{Charset: "utf-8"}
+	default:
+		if res.Data == nil {
+			return res, nil
+		}
+		encoded, err := json.Marshal(res.Data)
+		if err != nil {
+			return nil, fmt.Errorf("marshalling result data for compression: %w", err)
+		}
+		content, contentType = encoded, "application/json"
+		file = File{Charset: "utf-8"}
+	}
+
+	if len(content) < opts.MinSize || !isCompressible(contentType, opts.SkipContentTypes) {
+		return res, nil
+	}
+
+	compressed := *res
+	compressed.ResponseHeaders = cloneHeaders(res.ResponseHeaders)
+	addVary(compressed.ResponseHeaders, "Accept-Encoding")
+
+	encoding := negotiateEncoding(acceptEncoding)
+	if encoding == "" {
+		return &compressed, nil
+	}
+
+	data, err := compress(encoding, opts.Level, content)
+	if err != nil {
+		return nil, fmt.Errorf("compressing result: %w", err)
+	}
+	file.content = data
+	file.ContentType = contentType
+	file.SizeInBytes = int64(len(data))
+
+	compressed.ResponseHeaders["Content-Encoding"] = encoding
+	compressed.Type = ResultDataTypeBinary
+	compressed.Data = file
+	return &compressed, nil
+}
+
+// addVary adds the given header name to the Vary header, if it is not part of it yet.
+func addVary(headers map[string]string, name string) {
+	vary := headerValue(headers, "Vary")
+	for _, v := range strings.Split(vary, ",") {
+		if strings.EqualFold(strings.TrimSpace(v), name) {
+			return
+		}
+	}
+	if vary != "" {
+		name = vary + ", " + name
+	}
+	setHeader(headers, "Vary", name)
+}
+
+func isCompressible(contentType string, skip []string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	for _, exception := range compressibleExceptions {
+		if contentType == exception {
+			return true
+		}
+	}
+	for _, list := range [][]string{incompressibleContentTypes, skip} {
+		for _, prefix := range list {
+			if strings.HasPrefix(contentType, strings.ToLower(prefix)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// negotiateEncoding returns the supported content encoding with the highest quality value
+// in the given Accept-Encoding header. If none is acceptable, an empty string is returned.
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		qualities[coding] = q
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range []string{"gzip", "deflate"} {
+		q, ok := qualities[encoding]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > bestQuality {
+			best, bestQuality = encoding, q
+		}
+	}
+	return best
+}
+
+func compress(encoding string, level int, data []byte) ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+		err error
+	)
+	if encoding == "deflate" {
+		w, err = zlib.NewWriterLevel(&buf, level)
+	} else {
+		w, err = gzip.NewWriterLevel(&buf, level)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress decodes the data. If the decoded data exceeds maxSize bytes, [errDecompressedTooLarge] is returned.
+func decompress(encoding string, data []byte, maxSize int64) ([]byte, error) {
+	var (
+		r   io.ReadCloser
+		err error
+	)
+	if encoding == "deflate" {
+		r, err = zlib.NewReader(bytes.NewReader(data))
+	} else {
+		r, err = gzip.NewReader(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	content, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > maxSize {
+		return nil, errDecompressedTooLarge
+	}
+	return content, nil
+}
@@ -0,0 +1,183 @@
+package e5e_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+func buildPayload(t *testing.T, eventType e5e.EventDataType, data any, headers map[string]string) []byte {
+	t.Helper()
+	payload, err := json.Marshal(e5e.Request[any, any]{
+		Event: e5e.Event[any]{Type: eventType, Data: data, RequestHeaders: headers},
+	})
+	if err != nil {
+		t.Fatalf("marshalling payload failed: %v", err)
+	}
+	return payload
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, _ = w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatalf("compressing failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCompression(t *testing.T) {
+	t.Parallel()
+	largeText := strings.Repeat("Hello world! ", 200)
+
+	t.Run("binary requests are decompressed", func(t *testing.T) {
+		t.Parallel()
+		file := e5e.File{Name: "upload.txt"}
+		_, _ = file.Write(gzipBytes(t, []byte(largeText)))
+
+		handler := e5e.NewHandlerFactory[e5e.File, any](e5e.HandlerFunc[e5e.File, any](
+			func(ctx context.Context, r e5e.Request[e5e.File, any]) (*e5e.Result, error) {
+				Equal(t, largeText, string(r.Data().Bytes()), "content does not match")
+				Equal(t, int64(len(largeText)), r.Data().SizeInBytes, "file size does not match")
+				Equal(t, "", r.Event.Header("Content-Encoding"), "Content-Encoding header was not removed")
+				return &e5e.Result{Status: 204}, nil
+			}))
+
+		payload := buildPayload(t, e5e.EventDataTypeBinary, file, map[string]string{"content-encoding": "gzip"})
+		res, err := e5e.Compression(e5e.CompressionOptions{})(handler).Execute(context.Background(), payload)
+		if err != nil {
+			t.Fatalf("execution failed: %v", err)
+		}
+		Equal(t, 204, res.Status, "status does not match")
+	})
+	t.Run("invalid and unsupported encodings are rejected", func(t *testing.T) {
+		t.Parallel()
+		handler := e5e.HandlerFactoryFunc(func(context.Context, []byte) (*e5e.Result, error) {
+			t.Fatalf("handler must not be invoked")
+			return nil, nil
+		})
+
+		for encoding, status := range map[string]int{"gzip": 400, "br": 415} {
+			payload := buildPayload(t, e5e.EventDataTypeText, "not compressed", map[string]string{"Content-Encoding": encoding})
+			res, err := e5e.Compression(e5e.CompressionOptions{})(handler).Execute(context.Background(), payload)
+			if err != nil {
+				t.Fatalf("execution failed: %v", err)
+			}
+			Equal(t, status, res.Status, "status for "+encoding+" does not match")
+		}
+	})
+	t.Run("bodies exceeding the maximum size are rejected", func(t *testing.T) {
+		t.Parallel()
+		handler := e5e.HandlerFactoryFunc(func(context.Context, []byte) (*e5e.Result, error) {
+			t.Fatalf("handler must not be invoked")
+			return nil, nil
+		})
+
+		file := e5e.File{Name: "bomb.bin"}
+		_, _ = file.Write(gzipBytes(t, make([]byte, 1<<20)))
+		payload := buildPayload(t, e5e.EventDataTypeBinary, file, map[string]string{"Content-Encoding": "gzip"})
+		res, err := e5e.Compression(e5e.CompressionOptions{MaxDecompressedSize: 1 << 10})(handler).Execute(context.Background(), payload)
+		if err != nil {
+			t.Fatalf("execution failed: %v", err)
+		}
+		Equal(t, 413, res.Status, "status does not match")
+	})
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		result         e5e.Result
+		encoding       string
+		contentType    string
+		vary           bool
+	}{
+		{
+			name:           "gzip text",
+			acceptEncoding: "deflate;q=0.5, gzip",
+			result:         e5e.Result{Type: e5e.ResultDataTypeText, Data: largeText},
+			encoding:       "gzip",
+			contentType:    "text/plain",
+			vary:           true,
+		},
+		{
+			name:           "deflate object",
+			acceptEncoding: "gzip;q=0, deflate",
+			result:         e5e.Result{Data: map[string]string{"text": largeText}},
+			encoding:       "deflate",
+			contentType:    "application/json",
+			vary:           true,
+		},
+		{
+			name:           "not accepted",
+			acceptEncoding: "br",
+			result:         e5e.Result{Type: e5e.ResultDataTypeText, Data: largeText},
+			vary:           true,
+		},
+		{
+			name:           "too small",
+			acceptEncoding: "gzip",
+			result:         e5e.Result{Type: e5e.ResultDataTypeText, Data: "tiny"},
+		},
+		{
+			name:           "already compressed content type",
+			acceptEncoding: "gzip",
+			result: func() e5e.Result {
+				f := &e5e.File{ContentType: "image/png"}
+				_, _ = f.Write([]byte(largeText))
+				return e5e.Result{Type: e5e.ResultDataTypeBinary, Data: f}
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			handler := e5e.HandlerFactoryFunc(func(context.Context, []byte) (*e5e.Result, error) {
+				res := tt.result
+				return &res, nil
+			})
+			payload := buildPayload(t, e5e.EventDataTypeObject, nil, map[string]string{"Accept-Encoding": tt.acceptEncoding})
+			res, err := e5e.Compression(e5e.CompressionOptions{})(handler).Execute(context.Background(), payload)
+			if err != nil {
+				t.Fatalf("execution failed: %v", err)
+			}
+
+			Equal(t, tt.encoding, res.ResponseHeaders["Content-Encoding"], "Content-Encoding does not match")
+			Equal(t, tt.vary, res.ResponseHeaders["Vary"] == "Accept-Encoding", "Vary header does not match")
+			if tt.encoding == "" {
+				DeepEqual(t, tt.result.Data, res.Data, "uncompressed data got modified")
+				return
+			}
+
+			Equal(t, e5e.ResultDataTypeBinary, res.Type, "result type does not match")
+			file := res.Data.(e5e.File)
+			Equal(t, tt.contentType, file.ContentType, "content type does not match")
+
+			var r io.Reader
+			if tt.encoding == "gzip" {
+				r, err = gzip.NewReader(bytes.NewReader(file.Bytes()))
+			} else {
+				r, err = zlib.NewReader(bytes.NewReader(file.Bytes()))
+			}
+			if err != nil {
+				t.Fatalf("opening compressed data failed: %v", err)
+			}
+			content, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("decompressing data failed: %v", err)
+			}
+			if !strings.Contains(string(content), largeText) {
+				t.Errorf("decompressed content does not contain the original data")
+			}
+		})
+	}
+}
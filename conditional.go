@@ -0,0 +1,121 @@
+package e5e
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// notModifiedHeaders contains the response headers that are kept on a "304 Not Modified" result,
+// as required by RFC 9110, section 15.4.5.
+var notModifiedHeaders = []string{"Cache-Control", "Content-Location", "Date", "ETag", "Expires", "Last-Modified", "Vary"}
+
+// ETag computes a strong entity tag for the data of the given result.
+//
+// The tag is derived from the SHA-256 hash of the content for [File] data
+// and from the marshalled JSON representation for all other data.
+func ETag(res *Result) (string, error) {
+	h := sha256.New()
+	switch data := res.Data.(type) {
+	case File:
+		h.Write(data.content)
+	case *File:
+		// A nil file has no content.
+		if data != nil {
+			h.Write(data.content)
+		}
+	default:
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return "", fmt.Errorf("marshalling result data for the ETag: %w", err)
+		}
+		h.Write([]byte(res.Type))
+		h.Write(encoded)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`, nil
+}
+
+// ConditionalRequests returns a middleware that adds support for conditional requests.
+//
+// Every successful result gets an ETag response header, if the handler did not set one already.
+// If the If-None-Match request header matches this tag, or if the If-Modified-Since request header
+// is not before the Last-Modified response header set by the handler, the result is replaced
+// by a "304 Not Modified" result without any data.
+//
+// When combined with [Compression], add this middleware first, so the tag is computed
+// for the compressed representation.
+func ConditionalRequests() Middleware {
+	return func(next HandlerFactory) HandlerFactory {
+		return HandlerFactoryFunc(func(ctx context.Context, payload []byte) (*Result, error) {
+			request, err := decodeRawRequest(payload)
+			if err != nil {
+				return nil, err
+			}
+
+			res, err := next.Execute(ctx, payload)
+			if err != nil || res == nil {
+				return res, err
+			}
+			return evaluateConditional(request.Event.RequestHeaders, res)
+		})
+	}
+}
+
+// evaluateConditional sets the ETag on successful results and returns a "304 Not Modified" result,
+// if the preconditions in the request headers are met.
+func evaluateConditional(requestHeaders map[string]string, res *Result) (*Result, error) {
+	if res.Status != 0 && (res.Status < 200 || res.Status > 299) {
+		return res, nil
+	}
+
+	etag := headerValue(res.ResponseHeaders, "ETag")
+	if etag == "" {
+		var err error
+		if etag, err = ETag(res); err != nil {
+			return nil, err
+		}
+		tagged := *res
+		tagged.ResponseHeaders = setHeader(cloneHeaders(res.ResponseHeaders), "ETag", etag)
+		res = &tagged
+	}
+
+	if !isNotModified(requestHeaders, res.ResponseHeaders, etag) {
+		return res, nil
+	}
+
+	notModified := &Result{Status: 304, ResponseHeaders: make(map[string]string)}
+	for _, name := range notModifiedHeaders {
+		if v := headerValue(res.ResponseHeaders, name); v != "" {
+			notModified.ResponseHeaders[name] = v
+		}
+	}
+	return notModified, nil
+}
+
+func isNotModified(requestHeaders, responseHeaders map[string]string, etag string) bool {
+	// If-Modified-Since must be ignored if If-None-Match is present, see RFC 9110, section 13.1.3.
+	if ifNoneMatch := headerValue(requestHeaders, "If-None-Match"); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	ifModifiedSince, err := http.ParseTime(headerValue(requestHeaders, "If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(headerValue(responseHeaders, "Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(ifModifiedSince)
+}
@@ -0,0 +1,114 @@
+package e5e_test
+
+import (
+	"context"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	t.Parallel()
+
+	result := e5e.Result{
+		ResponseHeaders: map[string]string{
+			"Cache-Control": "max-age=60",
+			"Last-Modified": "Mon, 01 Jan 2024 12:00:00 GMT",
+			"X-Custom":      "custom",
+		},
+		Data: map[string]int{"a": 1},
+	}
+	etag, err := e5e.ETag(&result)
+	if err != nil {
+		t.Fatalf("computing ETag failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		result  e5e.Result
+		status  int
+	}{
+		{name: "unconditional", result: result},
+		{name: "matching ETag", headers: map[string]string{"If-None-Match": `"other", ` + etag}, result: result, status: 304},
+		{name: "weak matching ETag", headers: map[string]string{"if-none-match": "W/" + etag}, result: result, status: 304},
+		{name: "wildcard", headers: map[string]string{"If-None-Match": "*"}, result: result, status: 304},
+		{name: "different ETag", headers: map[string]string{"If-None-Match": `"other"`}, result: result},
+		{
+			name:    "not modified since",
+			headers: map[string]string{"If-Modified-Since": "Mon, 01 Jan 2024 12:00:00 GMT"},
+			result:  result,
+			status:  304,
+		},
+		{
+			name:    "modified since",
+			headers: map[string]string{"If-Modified-Since": "Mon, 01 Jan 2024 11:59:59 GMT"},
+			result:  result,
+		},
+		{
+			name: "If-None-Match takes precedence",
+			headers: map[string]string{
+				"If-None-Match":     `"other"`,
+				"If-Modified-Since": "Mon, 01 Jan 2024 12:00:00 GMT",
+			},
+			result: result,
+		},
+		{
+			name:    "failed results are ignored",
+			headers: map[string]string{"If-None-Match": "*"},
+			result:  e5e.Result{Status: 500, Data: "error"},
+			status:  500,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			handler := e5e.HandlerFactoryFunc(func(context.Context, []byte) (*e5e.Result, error) {
+				res := tt.result
+				return &res, nil
+			})
+			payload := buildPayload(t, e5e.EventDataTypeObject, nil, tt.headers)
+			res, err := e5e.ConditionalRequests()(handler).Execute(context.Background(), payload)
+			if err != nil {
+				t.Fatalf("execution failed: %v", err)
+			}
+
+			Equal(t, tt.status, res.Status, "status does not match")
+			switch tt.status {
+			case 304:
+				DeepEqual(t, nil, res.Data, "data is not empty")
+				Equal(t, etag, res.ResponseHeaders["ETag"], "ETag does not match")
+				Equal(t, "max-age=60", res.ResponseHeaders["Cache-Control"], "Cache-Control was not kept")
+				Equal(t, "", res.ResponseHeaders["X-Custom"], "X-Custom was kept")
+			case 0:
+				Equal(t, etag, res.ResponseHeaders["ETag"], "ETag does not match")
+				DeepEqual(t, tt.result.Data, res.Data, "data does not match")
+			default:
+				Equal(t, "", res.ResponseHeaders["ETag"], "ETag was set on a failed result")
+			}
+		})
+	}
+
+	t.Run("files are hashed by content", func(t *testing.T) {
+		t.Parallel()
+		a, b := &e5e.File{Name: "a.txt"}, &e5e.File{Name: "b.txt"}
+		_ = a.SetPlainText("same content")
+		_ = b.SetPlainText("same content")
+
+		etagA, _ := e5e.ETag(&e5e.Result{Type: e5e.ResultDataTypeBinary, Data: a})
+		etagB, _ := e5e.ETag(&e5e.Result{Type: e5e.ResultDataTypeBinary, Data: *b})
+		Equal(t, etagA, etagB, "ETags do not match")
+	})
+}
+
+func TestETagOfNilFile(t *testing.T) {
+	t.Parallel()
+
+	etag, err := e5e.ETag(&e5e.Result{Data: (*e5e.File)(nil)})
+	if err != nil {
+		t.Fatalf("computing ETag failed: %v", err)
+	}
+	empty, _ := e5e.ETag(&e5e.Result{Data: e5e.File{}})
+	Equal(t, empty, etag, "ETag of a nil file does not match the one of an empty file")
+}
@@ -0,0 +1,89 @@
+package e5e
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultDevServerAddr is the address the development server listens on, if none is given.
+const defaultDevServerAddr = "localhost:8080"
+
+// ListenAndServe starts a local HTTP server on the given address that emulates the E5E engine,
+// so functions can be tried with curl or a browser during development. It must not be used in production.
+//
+// The first segment of the URL path selects the entrypoint, e.g. "http://localhost:8080/Sum".
+// If only one entrypoint is registered, it's also served on "/".
+// Each HTTP request is converted into an [Event], handled by the registered handler including all middleware,
+// and the [Result] is written as HTTP response:
+//
+//   - The query parameters become the [Event.Params] and the headers become the [Event.RequestHeaders].
+//   - JSON bodies become [EventDataTypeObject], text/* bodies become [EventDataTypeText],
+//     multipart forms become [EventDataTypeMixed] and all other bodies become a [File] with [EventDataTypeBinary].
+//   - The result status, headers and data are written back, with a content type matching the [ResultDataType].
+//
+// If the handler returns an error, the response has status 500 and the error is logged to [os.Stderr].
+// The server is shut down gracefully once the context is cancelled.
+func ListenAndServe(ctx context.Context, addr string) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           globalMux.devHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownErr <- server.Shutdown(shutdownCtx)
+	}()
+
+	_, _ = fmt.Fprintf(os.Stderr, "go-e5e: serving entrypoints %s on http://%s\n", strings.Join(globalMux.entrypoints(), ", "), addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("go-e5e: development server: %w", err)
+	}
+	return <-shutdownErr
+}
+
+// DevHandler returns the [http.Handler] that's used by [ListenAndServe].
+// It can be used to serve the registered entrypoints with a custom [http.Server] or in tests.
+func DevHandler() http.Handler { return globalMux.devHandler() }
+
+// entrypoints returns the sorted names of all registered entrypoints.
+func (m *mux) entrypoints() []string {
+	names := make([]string, 0, len(m.handlers))
+	for name := range m.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m *mux) devHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entrypoint, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if _, ok := m.handlers[entrypoint]; !ok {
+			if entrypoints := m.entrypoints(); entrypoint == "" && len(entrypoints) == 1 {
+				entrypoint = entrypoints[0]
+			} else {
+				http.Error(w, InvalidEntrypointError{Entrypoint: entrypoint}.Error(), http.StatusNotFound)
+				return
+			}
+		}
+
+		handler := &httpHandler{factory: m.factory(entrypoint), opts: HTTPHandlerOptions{
+			MaxRequestBytes: maxRequestSize,
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				_, _ = fmt.Fprintf(os.Stderr, "go-e5e: executing handler %q: %v\n", entrypoint, err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			},
+		}}
+		handler.ServeHTTP(w, r)
+	})
+}
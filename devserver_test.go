@@ -0,0 +1,125 @@
+package e5e_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestDevServer(t *testing.T) {
+	// The echo handler returns the received event as the result, so we can inspect the conversion.
+	e5e.AddHandlerFunc("DevServerEcho", func(ctx context.Context, r e5e.Request[json.RawMessage, any]) (*e5e.Result, error) {
+		return &e5e.Result{
+			Status:          201,
+			ResponseHeaders: map[string]string{"X-Trigger": r.Context.Type},
+			Data:            r.Event,
+		}, nil
+	})
+	e5e.AddHandlerFunc("DevServerText", func(ctx context.Context, r e5e.Request[string, any]) (*e5e.Result, error) {
+		return &e5e.Result{Type: e5e.ResultDataTypeText, Data: strings.ToUpper(r.Data())}, nil
+	})
+	e5e.AddHandlerFunc("DevServerBinary", func(ctx context.Context, r e5e.Request[e5e.File, any]) (*e5e.Result, error) {
+		f := &e5e.File{ContentType: "application/x-test"}
+		_, _ = f.Write(append(r.Data().Bytes(), '!'))
+		return &e5e.Result{Type: e5e.ResultDataTypeBinary, Data: f}, nil
+	})
+	e5e.AddHandlerFunc("DevServerNilBinary", func(ctx context.Context, r e5e.Request[any, any]) (*e5e.Result, error) {
+		return &e5e.Result{Type: e5e.ResultDataTypeBinary, Data: (*e5e.File)(nil)}, nil
+	})
+	e5e.AddHandlerFunc("DevServerError", func(ctx context.Context, r e5e.Request[any, any]) (*e5e.Result, error) {
+		return nil, errors.New("handler failed")
+	})
+
+	server := httptest.NewServer(e5e.DevHandler())
+	t.Cleanup(server.Close)
+
+	do := func(t *testing.T, path, contentType string, body io.Reader) (*http.Response, []byte) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, server.URL+path, body)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("X-Custom", "custom")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return resp, respBody
+	}
+
+	t.Run("JSON object", func(t *testing.T) {
+		resp, body := do(t, "/DevServerEcho?a=1&a=2", "application/json", strings.NewReader(`{"x":1}`))
+		Equal(t, 201, resp.StatusCode, "status does not match")
+		Equal(t, "http", resp.Header.Get("X-Trigger"), "trigger type does not match")
+		Equal(t, "application/json", resp.Header.Get("Content-Type"), "content type does not match")
+
+		var event e5e.Event[json.RawMessage]
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Fatalf("decoding event failed: %v", err)
+		}
+		Equal(t, e5e.EventDataTypeObject, event.Type, "event type does not match")
+		Equal(t, `{"x":1}`, string(event.Data), "event data does not match")
+		DeepEqual(t, []string{"1", "2"}, event.Params["a"], "params do not match")
+		Equal(t, "custom", event.Header("x-custom"), "request header does not match")
+	})
+	t.Run("nil binary result", func(t *testing.T) {
+		resp, body := do(t, "/DevServerNilBinary", "application/json", strings.NewReader(`{}`))
+		Equal(t, 200, resp.StatusCode, "status does not match")
+		Equal(t, "", string(body), "body does not match")
+	})
+	t.Run("multipart form", func(t *testing.T) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		_ = mw.WriteField("name", "value")
+		fw, _ := mw.CreateFormFile("upload", "hello.txt")
+		_, _ = fw.Write([]byte("Hello world!"))
+		_ = mw.Close()
+
+		_, body := do(t, "/DevServerEcho", mw.FormDataContentType(), &buf)
+		var event e5e.Event[map[string][]json.RawMessage]
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Fatalf("decoding event failed: %v", err)
+		}
+		Equal(t, e5e.EventDataTypeMixed, event.Type, "event type does not match")
+		Equal(t, `"value"`, string(event.Data["name"][0]), "form value does not match")
+
+		var file e5e.File
+		if err := json.Unmarshal(event.Data["upload"][0], &file); err != nil {
+			t.Fatalf("decoding file failed: %v", err)
+		}
+		Equal(t, "hello.txt", file.Name, "file name does not match")
+		Equal(t, "Hello world!", string(file.Bytes()), "file content does not match")
+	})
+	t.Run("text", func(t *testing.T) {
+		resp, body := do(t, "/DevServerText", "text/plain", strings.NewReader("hello"))
+		Equal(t, 200, resp.StatusCode, "status does not match")
+		Equal(t, "text/plain; charset=utf-8", resp.Header.Get("Content-Type"), "content type does not match")
+		Equal(t, "HELLO", string(body), "body does not match")
+	})
+	t.Run("binary", func(t *testing.T) {
+		resp, body := do(t, "/DevServerBinary", "application/octet-stream", strings.NewReader("data"))
+		Equal(t, "application/x-test", resp.Header.Get("Content-Type"), "content type does not match")
+		Equal(t, "data!", string(body), "body does not match")
+	})
+	t.Run("handler error", func(t *testing.T) {
+		resp, _ := do(t, "/DevServerError", "text/plain", nil)
+		Equal(t, 500, resp.StatusCode, "status does not match")
+	})
+	t.Run("unknown entrypoint", func(t *testing.T) {
+		resp, _ := do(t, "/DoesNotExist", "text/plain", nil)
+		Equal(t, 404, resp.StatusCode, "status does not match")
+	})
+	t.Run("invalid JSON", func(t *testing.T) {
+		resp, _ := do(t, "/DevServerEcho", "application/json", strings.NewReader("{"))
+		Equal(t, 400, resp.StatusCode, "status does not match")
+	})
+}
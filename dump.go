@@ -0,0 +1,83 @@
+package e5e
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Environment variables that control the debug dump, see the package documentation.
+const (
+	envDebugDump              = "E5E_DEBUG_DUMP"
+	envDebugRedactHeaders     = "E5E_DEBUG_REDACT_HEADERS"
+	envDebugRedactContextData = "E5E_DEBUG_REDACT_CONTEXT_DATA"
+	envDebugRedactPaths       = "E5E_DEBUG_REDACT_PATHS"
+)
+
+// debugDumper writes the raw request envelope and the encoded response of each execution to [os.Stderr],
+// see the package documentation.
+type debugDumper struct {
+	redactor redactor
+}
+
+// debugDump is the line written for each execution.
+type debugDump struct {
+	Time       time.Time `json:"time"`
+	Level      string    `json:"level"`
+	Msg        string    `json:"msg"`
+	Entrypoint string    `json:"entrypoint"`
+	Request    any       `json:"request"`
+	Response   any       `json:"response,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// debugDumperFromEnv returns a dumper, if the debug dump is enabled by the environment, or nil otherwise.
+func debugDumperFromEnv() (*debugDumper, error) {
+	v := os.Getenv(envDebugDump)
+	if v == "" {
+		return nil, nil
+	}
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, fmt.Errorf("go-e5e: invalid %s %q", envDebugDump, v)
+	}
+	if !enabled {
+		return nil, nil
+	}
+	r := redactorFromEnv(envDebugRedactHeaders, envDebugRedactContextData, envDebugRedactPaths)
+	r.truncateFiles = true
+	return &debugDumper{redactor: r}, nil
+}
+
+// dump writes the execution to the log buffer, which is flushed before the daemon termination sequence.
+func (d *debugDumper) dump(entrypoint string, request []byte, response string, err error) {
+	line := debugDump{
+		Time:       time.Now(),
+		Level:      "DEBUG",
+		Msg:        "debug dump",
+		Entrypoint: entrypoint,
+		Request:    d.document(request),
+	}
+	if err != nil {
+		line.Error = err.Error()
+	} else {
+		line.Response = d.document([]byte(response))
+	}
+
+	encoded, marshalErr := json.Marshal(line)
+	if marshalErr != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "go-e5e: encoding debug dump failed: %v\n", marshalErr)
+		return
+	}
+	_, _ = logOutput.Write(append(encoded, '\n'))
+}
+
+// document returns the redacted JSON document, or the document as string, if it's not valid JSON.
+func (d *debugDumper) document(document []byte) any {
+	if !json.Valid(document) {
+		return string(document)
+	}
+	return json.RawMessage(d.redactor.redact(document))
+}
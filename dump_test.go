@@ -0,0 +1,79 @@
+package e5e_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestDebugDump(t *testing.T) {
+	t.Setenv("E5E_DEBUG_DUMP", "true")
+	t.Setenv("E5E_DEBUG_REDACT_CONTEXT_DATA", "api_key")
+	t.Setenv("E5E_DEBUG_REDACT_PATHS", "event.params.token")
+
+	e5e.AddHandlerFunc(t.Name(), func(ctx context.Context, r e5e.Request[e5e.File, map[string]string]) (*e5e.Result, error) {
+		return &e5e.Result{Data: r.Data()}, nil
+	})
+
+	content := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("0123456789", 10)))
+	payload, _ := json.Marshal(map[string]any{
+		"event": map[string]any{
+			"type":            "binary",
+			"data":            map[string]any{"type": "binary", "binary": content, "name": "numbers.txt"},
+			"params":          map[string][]string{"token": {"secret"}, "page": {"1"}},
+			"request_headers": map[string]string{"authorization": "Bearer secret", "X-Request-Id": "abc"},
+		},
+		"context": map[string]any{"type": "http", "data": map[string]string{"API_KEY": "secret", "region": "at"}},
+	})
+
+	stdio := redirectStdio(t, "ping\n"+string(payload)+"\n")
+	os.Args = buildOptions(t.Name())
+	os.Args[3] = "1"
+	e5e.Start(context.Background())
+	_, stderr := stdio.ReadAndRestore()
+
+	frames := strings.Split(stderr, daemonTerminationSequence)
+	Equal(t, 3, len(frames), "number of stderr frames does not match")
+	Equal(t, "", frames[0], "control messages must not be dumped")
+
+	var dump struct {
+		Msg        string `json:"msg"`
+		Entrypoint string `json:"entrypoint"`
+		Request    struct {
+			Event struct {
+				Data           map[string]any    `json:"data"`
+				Params         map[string]any    `json:"params"`
+				RequestHeaders map[string]string `json:"request_headers"`
+			} `json:"event"`
+			Context struct {
+				Data map[string]string `json:"data"`
+			} `json:"context"`
+		} `json:"request"`
+		Response struct {
+			Result struct {
+				Data map[string]any `json:"data"`
+			} `json:"result"`
+		} `json:"response"`
+	}
+	if err := json.Unmarshal([]byte(frames[1]), &dump); err != nil {
+		t.Fatalf("dump %q is not valid JSON: %v", frames[1], err)
+	}
+
+	truncated := content[:32] + "...[104 more characters]"
+	Equal(t, "debug dump", dump.Msg, "message does not match")
+	Equal(t, t.Name(), dump.Entrypoint, "entrypoint does not match")
+	Equal[any](t, truncated, dump.Request.Event.Data["binary"], "request file was not truncated")
+	Equal[any](t, "numbers.txt", dump.Request.Event.Data["name"], "file name does not match")
+	Equal[any](t, "[REDACTED]", dump.Request.Event.Params["token"], "param was not redacted")
+	DeepEqual(t, []any{"1"}, dump.Request.Event.Params["page"], "param was redacted")
+	Equal(t, "[REDACTED]", dump.Request.Event.RequestHeaders["authorization"], "header was not redacted")
+	Equal(t, "abc", dump.Request.Event.RequestHeaders["X-Request-Id"], "header was redacted")
+	Equal(t, "[REDACTED]", dump.Request.Context.Data["API_KEY"], "context data was not redacted")
+	Equal(t, "at", dump.Request.Context.Data["region"], "context data was redacted")
+	Equal[any](t, truncated, dump.Response.Result.Data["binary"], "response file was not truncated")
+}
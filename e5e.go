@@ -3,9 +3,56 @@
 //
 // It provides a simple runtime which properly handles the input from the runtime.
 //
+// # Memory limits
+//
+// The memory limit is read from the cgroup of the process or from the environment variable E5E_MEMORY_LIMIT,
+// e.g. "512MiB". Unless GOMEMLIMIT is set, 90% of it are set as limit of the Go runtime, see [debug.SetMemoryLimit].
+// While a handler runs, the memory usage is monitored. Once it exceeds the share of the limit given by
+// E5E_MEMORY_SOFT_LIMIT (default "0.95", "0" disables the monitoring), the context is cancelled with
+// [ErrMemoryLimitExceeded] and the runtime responds with the status 503 instead of the result of the handler,
+// so the caller gets a meaningful error instead of the process being killed.
+//
+// In keepalive mode, E5E_MEMORY_RELEASE can be set to "gc" for running the garbage collector or to "free"
+// for returning as much memory as possible to the operating system (see [debug.FreeOSMemory]) between invocations.
+//
+// # Profiling
+//
+// In keepalive mode, the following lines are not passed to the handler, but start a profile:
+//
+//   - "profile cpu [duration]" records a CPU profile for the duration (default "10s").
+//   - "profile trace [duration]" records an execution trace for the duration (default "10s").
+//   - "profile <name>" writes a snapshot of the named profile, like "heap", "goroutine" or "allocs",
+//     see [pprof.Profile] for all names.
+//
+// CPU profiles and traces are recorded in the background, so the daemon continues to execute events meanwhile.
+// The runtime acknowledges the message with a JSON object that contains the path of the profile,
+// e.g. {"profile":"cpu","file":"/tmp/cpu-20240311T102030.000Z.pprof","duration":"10s"}, or the error.
+// The profiles are written to the directory given by the environment variable E5E_PROFILE_DIR,
+// which defaults to the temporary directory.
+//
+// # Debug dump
+//
+// The dump is enabled by setting the environment variable E5E_DEBUG_DUMP to "true" or "1".
+// The raw request envelope and the encoded response of each execution are then written to [os.Stderr]
+// as a single JSON line after the execution finished, together with the logs, so it's always part of the frame
+// of the execution. Sensitive values are redacted like in a [Recording]:
+//
+//   - E5E_DEBUG_REDACT_HEADERS contains a comma-separated list of request and response headers.
+//     If it is not set, the Authorization, Cookie, Proxy-Authorization and Set-Cookie headers are redacted.
+//   - E5E_DEBUG_REDACT_CONTEXT_DATA contains a comma-separated list of keys of the context data.
+//   - E5E_DEBUG_REDACT_PATHS contains a comma-separated list of dot-separated JSON paths inside the request
+//     or the response.
+//
+// The base64 encoded contents of files are truncated to their first characters.
+//
 // [Anexia Engine]: https://engine.anexia-it.com/docs/en/module/e5e/
 package e5e // import "go.anx.io/e5e/v2"
 
+import (
+	"fmt"
+	"time"
+)
+
 // EventDataType tells more information about the type of the data inside an [Event].
 type EventDataType string
 
@@ -80,6 +127,24 @@ type Context[T Data] struct {
 	Data T `json:"data,omitempty"`
 }
 
+// contextDateLayouts contains the layouts that are tried in order to parse [Context.Date].
+// E5E usually omits the timezone, in which case UTC is assumed.
+var contextDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+}
+
+// Time parses [Context.Date] and returns the time the event was triggered.
+// If the date does not contain a timezone, UTC is assumed.
+func (c Context[T]) Time() (time.Time, error) {
+	for _, layout := range contextDateLayouts {
+		if t, err := time.Parse(layout, c.Date); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("context date %q has an unknown format", c.Date)
+}
+
 // Request contains the whole request information.
 type Request[T, TContext Data] struct {
 	Context Context[TContext] `json:"context"`
@@ -137,6 +202,7 @@ type options struct {
 	// The execution sequence that separates generic output on [os.Stdout] from the encoded responses.
 	StdoutExecutionSequence string
 
-	// If set to true, the application is kept alive after the first execution and responds to ping events.
+	// If set to true, the application is kept alive after the first execution and responds to control messages,
+	// like "ping", "metrics" or "profile cpu 30s" (see [WriteMetrics] and the README).
 	KeepAlive bool
 }
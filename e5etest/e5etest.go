@@ -0,0 +1,185 @@
+// Package e5etest provides utilities for testing e5e handlers in-process.
+//
+// A request is built using [NewRequest] and passed to a handler with [Invoke] or [InvokeFunc].
+// The handler is executed the same way the e5e runtime does, see [e5e.Invocation]: it's wrapped with the middleware
+// added by [e5e.Use], gets the context of an invocation, like [e5e.RequestID] and [e5e.Logger], and the request
+// and the result are encoded like E5E does, so the returned [Response] reflects what E5E would receive.
+//
+//	func TestSum(t *testing.T) {
+//		req := e5etest.NewRequest().WithData(SumData{A: 2, B: 3})
+//		e5etest.InvokeFunc(t, Sum, req).
+//			AssertNoError().
+//			AssertStatus(200).
+//			AssertData(5)
+//	}
+//
+// For regression tests, results can be compared with golden files using [Golden] or [Response.AssertGolden].
+// Handlers are turned into fuzz targets with [Fuzz].
+package e5etest // import "go.anx.io/e5e/v2/e5etest"
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.anx.io/e5e/v2"
+)
+
+// entrypoint is the name of the entrypoint handlers are executed as, e.g. in the logs and metrics.
+const entrypoint = "e5etest"
+
+// RequestBuilder builds a request envelope, as it would be sent by E5E.
+// All methods modify the builder in place and return it, so calls can be chained.
+type RequestBuilder struct {
+	request e5e.Request[any, any]
+}
+
+// NewRequest returns a builder for a synchronous request with no data,
+// triggered at the current time by a trigger of the type "e5etest".
+func NewRequest() *RequestBuilder {
+	return &RequestBuilder{
+		request: e5e.Request[any, any]{
+			Context: e5e.Context[any]{
+				Date: time.Now().UTC().Format(time.RFC3339Nano),
+				Type: "e5etest",
+			},
+		},
+	}
+}
+
+// WithData sets the event data to the given value and the event type to [e5e.EventDataTypeObject].
+func (b *RequestBuilder) WithData(v any) *RequestBuilder {
+	b.request.Event.Type = e5e.EventDataTypeObject
+	b.request.Event.Data = v
+	return b
+}
+
+// WithText sets the event data to the given text and the event type to [e5e.EventDataTypeText].
+func (b *RequestBuilder) WithText(text string) *RequestBuilder {
+	b.request.Event.Type = e5e.EventDataTypeText
+	b.request.Event.Data = text
+	return b
+}
+
+// WithFile sets the event data to the given file and the event type to [e5e.EventDataTypeBinary].
+func (b *RequestBuilder) WithFile(f e5e.File) *RequestBuilder {
+	b.request.Event.Type = e5e.EventDataTypeBinary
+	b.request.Event.Data = f
+	return b
+}
+
+// WithFormField adds a value for the given field and sets the event type to [e5e.EventDataTypeMixed].
+// The value may be a primitive value or an [e5e.File]. Any data set before that's not mixed data is discarded.
+func (b *RequestBuilder) WithFormField(name string, value any) *RequestBuilder {
+	fields, ok := b.request.Event.Data.(map[string][]any)
+	if !ok || b.request.Event.Type != e5e.EventDataTypeMixed {
+		fields = make(map[string][]any)
+	}
+	fields[name] = append(fields[name], value)
+
+	b.request.Event.Type = e5e.EventDataTypeMixed
+	b.request.Event.Data = fields
+	return b
+}
+
+// WithType overrides the type of the event data.
+func (b *RequestBuilder) WithType(t e5e.EventDataType) *RequestBuilder {
+	b.request.Event.Type = t
+	return b
+}
+
+// WithParam adds a value for the given GET parameter.
+func (b *RequestBuilder) WithParam(key, value string) *RequestBuilder {
+	if b.request.Event.Params == nil {
+		b.request.Event.Params = make(map[string][]string)
+	}
+	b.request.Event.Params[key] = append(b.request.Event.Params[key], value)
+	return b
+}
+
+// WithHeader sets the given request header.
+func (b *RequestBuilder) WithHeader(key, value string) *RequestBuilder {
+	if b.request.Event.RequestHeaders == nil {
+		b.request.Event.RequestHeaders = make(map[string]string)
+	}
+	b.request.Event.RequestHeaders[key] = value
+	return b
+}
+
+// WithContextData sets the additional data of the context.
+func (b *RequestBuilder) WithContextData(v any) *RequestBuilder {
+	b.request.Context.Data = v
+	return b
+}
+
+// WithTrigger sets the kind of trigger that triggered the execution.
+func (b *RequestBuilder) WithTrigger(triggerType string) *RequestBuilder {
+	b.request.Context.Type = triggerType
+	return b
+}
+
+// WithAsync marks the request as triggered asynchronously.
+func (b *RequestBuilder) WithAsync(async bool) *RequestBuilder {
+	b.request.Context.Async = async
+	return b
+}
+
+// WithDate sets the time the event was triggered.
+func (b *RequestBuilder) WithDate(date time.Time) *RequestBuilder {
+	b.request.Context.Date = date.UTC().Format(time.RFC3339Nano)
+	return b
+}
+
+// Build returns the request envelope.
+func (b *RequestBuilder) Build() e5e.Request[any, any] { return b.request }
+
+// Payload returns the JSON encoding of the request envelope, as it's sent by E5E on [os.Stdin].
+func (b *RequestBuilder) Payload() ([]byte, error) { return json.Marshal(b.request) }
+
+// Invoke executes the handler with the given request, the same way the runtime does.
+// If the request cannot be encoded, the test fails immediately.
+func Invoke[T, TContext e5e.Data](t testing.TB, h e5e.Handler[T, TContext], req *RequestBuilder) *Response {
+	t.Helper()
+	return InvokeFactory(t, e5e.NewHandlerFactory(h), req)
+}
+
+// InvokeFunc works like [Invoke], but takes a handler function like [e5e.AddHandlerFunc].
+func InvokeFunc[T, TContext e5e.Data](t testing.TB, fn func(context.Context, e5e.Request[T, TContext]) (*e5e.Result, error), req *RequestBuilder) *Response {
+	t.Helper()
+	return InvokeFactory(t, e5e.NewHandlerFactory[T, TContext](e5e.HandlerFunc[T, TContext](fn)), req)
+}
+
+// InvokeFactory works like [Invoke], but takes a [e5e.HandlerFactory], e.g. a handler that's wrapped with middleware.
+func InvokeFactory(t testing.TB, f e5e.HandlerFactory, req *RequestBuilder) *Response {
+	t.Helper()
+
+	payload, err := req.Payload()
+	if err != nil {
+		t.Fatalf("e5etest: encoding request failed: %v", err)
+	}
+
+	resp := &Response{t: t}
+	invocation := e5e.Invocation(entrypoint, e5e.HandlerFactoryFunc(func(ctx context.Context, payload []byte) (*e5e.Result, error) {
+		resp.RequestID = e5e.RequestID(ctx)
+		return f.Execute(ctx, payload)
+	}))
+	resp.Result, resp.Err = invocation.Execute(context.Background(), payload)
+	if resp.Err != nil {
+		return resp
+	}
+
+	resp.Raw, resp.Err = e5e.MarshalResult(resp.Result)
+	if resp.Err != nil {
+		return resp
+	}
+
+	var decoded struct {
+		Result *decodedResult `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Raw, &decoded); err != nil {
+		t.Fatalf("e5etest: decoding response failed: %v", err)
+	}
+	resp.decoded = decoded.Result
+	return resp
+}
@@ -0,0 +1,162 @@
+package e5etest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+	"go.anx.io/e5e/v2/e5etest"
+)
+
+type SumData struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+type AuthContext struct {
+	AuthKey string `json:"auth_key"`
+}
+
+func Sum(_ context.Context, r e5e.Request[SumData, AuthContext]) (*e5e.Result, error) {
+	if r.Context.Data.AuthKey != "secret" {
+		return nil, errors.New("unauthorized")
+	}
+	return &e5e.Result{
+		Status:          200,
+		ResponseHeaders: map[string]string{"X-Params": fmt.Sprint(r.Event.Params["mode"])},
+		Data:            map[string]int{"sum": r.Data().A + r.Data().B},
+	}, nil
+}
+
+// recordingTB records failures instead of failing the actual test.
+type recordingTB struct {
+	testing.TB
+	mu       sync.Mutex
+	failures []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// run executes fn in a separate goroutine, so calls to Fatalf do not abort the actual test.
+func (r *recordingTB) run(fn func()) []string {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	<-done
+	return r.failures
+}
+
+func TestInvoke(t *testing.T) {
+	t.Parallel()
+
+	t.Run("successful invocation", func(t *testing.T) {
+		t.Parallel()
+		req := e5etest.NewRequest().
+			WithData(SumData{A: 2, B: 3}).
+			WithParam("mode", "a").
+			WithParam("mode", "b").
+			WithContextData(AuthContext{AuthKey: "secret"})
+
+		e5etest.InvokeFunc(t, Sum, req).
+			AssertNoError().
+			AssertStatus(200).
+			AssertHeader("x-params", "[a b]").
+			AssertData(map[string]int{"sum": 5}).
+			AssertJSON(`{"sum": 5}`)
+	})
+	t.Run("handler error", func(t *testing.T) {
+		t.Parallel()
+		req := e5etest.NewRequest().WithData(SumData{A: 2, B: 3})
+		e5etest.InvokeFunc(t, Sum, req).AssertError("unauthorized")
+	})
+	t.Run("struct handler and files", func(t *testing.T) {
+		t.Parallel()
+		file := e5e.File{Name: "input.txt"}
+		_ = file.SetPlainText("Hello world!")
+
+		handler := e5e.HandlerFunc[e5e.File, any](func(_ context.Context, r e5e.Request[e5e.File, any]) (*e5e.Result, error) {
+			if r.Event.Header("Content-Type") != "text/plain" || r.Event.Type != e5e.EventDataTypeBinary {
+				return nil, errors.New("unexpected request")
+			}
+			return &e5e.Result{Type: e5e.ResultDataTypeBinary, Data: r.Data()}, nil
+		})
+		req := e5etest.NewRequest().WithFile(file).WithHeader("Content-Type", "text/plain")
+
+		resp := e5etest.Invoke[e5e.File, any](t, handler, req).AssertFile([]byte("Hello world!"))
+		Equal(t, "input.txt", resp.File().Name, "file name does not match")
+	})
+	t.Run("form fields", func(t *testing.T) {
+		t.Parallel()
+		req := e5etest.NewRequest().WithFormField("tag", "a").WithFormField("tag", 2)
+		Equal(t, e5e.EventDataTypeMixed, req.Build().Event.Type, "event type does not match")
+
+		e5etest.InvokeFunc(t, func(_ context.Context, r e5e.Request[map[string][]any, any]) (*e5e.Result, error) {
+			return &e5e.Result{Data: r.Data()["tag"]}, nil
+		}, req).AssertJSON(`["a", 2]`)
+	})
+	t.Run("failed assertions are reported", func(t *testing.T) {
+		t.Parallel()
+		tb := &recordingTB{TB: t}
+		failures := tb.run(func() {
+			req := e5etest.NewRequest().WithData(SumData{A: 1, B: 1}).WithContextData(AuthContext{AuthKey: "secret"})
+			e5etest.InvokeFunc(tb, Sum, req).
+				AssertStatus(201).
+				AssertHeader("X-Params", "unexpected").
+				AssertData(map[string]int{"sum": 3}).
+				AssertType(e5e.ResultDataTypeBinary).
+				AssertError("anything")
+		})
+		Equal(t, 5, len(failures), fmt.Sprintf("number of failures does not match: %q", failures))
+	})
+}
+
+// middlewareContextKey marks the context of executions that went through the middleware added in [TestInvokeInvocation].
+type middlewareContextKey struct{}
+
+func TestInvokeInvocation(t *testing.T) {
+	e5e.Use(func(next e5e.HandlerFactory) e5e.HandlerFactory {
+		return e5e.HandlerFactoryFunc(func(ctx context.Context, payload []byte) (*e5e.Result, error) {
+			return next.Execute(context.WithValue(ctx, middlewareContextKey{}, true), payload)
+		})
+	})
+	handler := func(ctx context.Context, r e5e.Request[any, any]) (*e5e.Result, error) {
+		if ctx.Value(middlewareContextKey{}) != true {
+			return nil, errors.New("middleware was not applied")
+		}
+		return &e5e.Result{Data: e5e.RequestID(ctx)}, nil
+	}
+
+	resp := e5etest.InvokeFunc(t, handler, e5etest.NewRequest().WithHeader("X-Request-Id", "test-request-id")).
+		AssertNoError().
+		AssertHeader("X-Request-Id", "test-request-id").
+		AssertData("test-request-id")
+	Equal(t, "test-request-id", resp.RequestID, "request ID does not match")
+
+	resp = e5etest.InvokeFunc(t, handler, e5etest.NewRequest()).AssertNoError()
+	Equal(t, 32, len(resp.RequestID), "request ID must be generated")
+	resp.AssertHeader("X-Request-Id", resp.RequestID).AssertData(resp.RequestID)
+}
+
+func Equal[T comparable](t *testing.T, expected, actual T, message string) {
+	t.Helper()
+	if actual != expected {
+		t.Fatalf("%s:\n\tgot:\t%v\n\twanted:\t%v", message, actual, expected)
+	}
+}
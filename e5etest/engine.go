@@ -0,0 +1,468 @@
+package e5etest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.anx.io/e5e/v2"
+)
+
+// Default sequences passed to the function binary by [StartEngine]. They use the same escaping as E5E,
+// where "\0" stands for a NUL byte.
+const (
+	DefaultStdoutSequence = `\0\0\0e5e-stdout\0\0\0`
+	DefaultDaemonSequence = `\0\0\0e5e-done\0\0\0`
+)
+
+// EngineOptions configure how [StartEngine] launches a function binary.
+type EngineOptions struct {
+	// The entrypoint passed to the binary.
+	Entrypoint string
+
+	// If set to true, the binary is kept alive after the first execution and can receive multiple events and pings.
+	KeepAlive bool
+
+	// The sequence that separates generic output from the response on stdout. Defaults to [DefaultStdoutSequence].
+	StdoutSequence string
+
+	// The sequence that terminates an execution in keepalive mode. Defaults to [DefaultDaemonSequence].
+	DaemonSequence string
+
+	// The maximum time to wait for the response of a single execution. Defaults to 10 seconds.
+	Timeout time.Duration
+
+	// Additional environment variables in the form "key=value", appended to the environment of the test.
+	Env []string
+}
+
+// Execution contains the outcome of sending a single line to the function binary.
+type Execution struct {
+	// The line that was sent.
+	Input []byte
+
+	// The generic output on stdout, that was written before the stdout sequence, e.g. by fmt.Print.
+	Stdout string
+
+	// The raw response after the stdout sequence.
+	Response []byte
+
+	// The output on stderr during this execution.
+	Stderr string
+
+	// The time between sending the line and receiving the complete response.
+	Duration time.Duration
+
+	// All violations of the protocol detected during this execution.
+	Violations []string
+}
+
+// Result decodes the response into a result, whose data is kept as [json.RawMessage].
+// It returns an error for responses that are not a result, e.g. "pong".
+func (x Execution) Result() (*e5e.Result, error) {
+	var wrapped struct {
+		Result *decodedResult `json:"result"`
+	}
+	if err := json.Unmarshal(x.Response, &wrapped); err != nil {
+		return nil, err
+	}
+	if wrapped.Result == nil {
+		return nil, nil
+	}
+	return &e5e.Result{
+		Status:          wrapped.Result.Status,
+		ResponseHeaders: wrapped.Result.ResponseHeaders,
+		Data:            wrapped.Result.Data,
+		Type:            wrapped.Result.Type,
+	}, nil
+}
+
+// DecodeResult decodes the data of the result into v.
+func (x Execution) DecodeResult(v any) error {
+	res, err := x.Result()
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		return errors.New("response does not contain a result")
+	}
+	return json.Unmarshal(res.Data.(json.RawMessage), v)
+}
+
+// Report summarizes a whole run of the function binary, see [Engine.Close].
+type Report struct {
+	Executions []Execution
+
+	// All violations of the protocol, including the ones of the executions.
+	Violations []string
+
+	// The time between launching the binary and the first response.
+	StartupDuration time.Duration
+
+	// The exit code of the binary, or -1 if it had to be killed.
+	ExitCode int
+}
+
+// Engine drives a function binary over stdin and stdout, the same way as the e5e engine does,
+// and checks that the binary follows the protocol. It's used to test the actual binary out-of-process:
+//
+//	func TestBinary(t *testing.T) {
+//		binary := e5etest.BuildBinary(t, ".")
+//		engine := e5etest.StartEngine(t, binary, e5etest.EngineOptions{Entrypoint: "Sum", KeepAlive: true})
+//		engine.Ping()
+//		engine.Send(e5etest.NewRequest().WithData(SumData{A: 2, B: 3}))
+//		engine.AssertConformance()
+//	}
+type Engine struct {
+	t    testing.TB
+	opts EngineOptions
+
+	stdoutSequence []byte
+	daemonSequence []byte
+
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  *streamBuffer
+	stderr  *streamBuffer
+	exited  chan struct{}
+	started time.Time
+
+	report Report
+	closed bool
+}
+
+// BuildBinary builds the main package at the given path, e.g. ".", into a temporary directory
+// and returns the path of the binary. The test fails if the build fails.
+func BuildBinary(t testing.TB, pkg string) string {
+	t.Helper()
+	binary := filepath.Join(t.TempDir(), "e5e-function")
+	cmd := exec.Command("go", "build", "-o", binary, pkg)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("e5etest: building %s failed: %v\n%s", pkg, err, out)
+	}
+	return binary
+}
+
+// StartEngine launches the given binary with the arguments the e5e engine would pass.
+// The binary is stopped when the test finishes, if [Engine.Close] was not called before.
+func StartEngine(t testing.TB, binary string, opts EngineOptions) *Engine {
+	t.Helper()
+	if opts.StdoutSequence == "" {
+		opts.StdoutSequence = DefaultStdoutSequence
+	}
+	if opts.DaemonSequence == "" {
+		opts.DaemonSequence = DefaultDaemonSequence
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	keepAlive := "0"
+	if opts.KeepAlive {
+		keepAlive = "1"
+	}
+
+	e := &Engine{
+		t:              t,
+		opts:           opts,
+		stdoutSequence: []byte(strings.ReplaceAll(opts.StdoutSequence, `\0`, "\x00")),
+		daemonSequence: []byte(strings.ReplaceAll(opts.DaemonSequence, `\0`, "\x00")),
+		stdout:         newStreamBuffer(),
+		stderr:         newStreamBuffer(),
+		exited:         make(chan struct{}),
+	}
+	e.cmd = exec.Command(binary, opts.Entrypoint, opts.StdoutSequence, keepAlive, opts.DaemonSequence)
+	e.cmd.Env = append(os.Environ(), opts.Env...)
+
+	var err error
+	if e.stdin, err = e.cmd.StdinPipe(); err != nil {
+		t.Fatalf("e5etest: creating stdin pipe failed: %v", err)
+	}
+	stdout, err := e.cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("e5etest: creating stdout pipe failed: %v", err)
+	}
+	stderr, err := e.cmd.StderrPipe()
+	if err != nil {
+		t.Fatalf("e5etest: creating stderr pipe failed: %v", err)
+	}
+
+	e.started = time.Now()
+	if err := e.cmd.Start(); err != nil {
+		t.Fatalf("e5etest: starting %s failed: %v", binary, err)
+	}
+	go e.stdout.readFrom(stdout)
+	go e.stderr.readFrom(stderr)
+	go func() {
+		// Wait must not be called before all output has been read.
+		<-e.stdout.done
+		<-e.stderr.done
+		_ = e.cmd.Wait()
+		close(e.exited)
+	}()
+
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+// Ping sends a ping and checks that the binary answers with "pong". It's only valid in keepalive mode.
+func (e *Engine) Ping() Execution {
+	e.t.Helper()
+	x := e.SendRaw([]byte("ping"))
+	if len(x.Violations) == 0 && string(x.Response) != "pong" {
+		e.addViolation(&x, fmt.Sprintf("expected %q as response to a ping, got %q", "pong", x.Response))
+	}
+	return x
+}
+
+// Metrics requests the metrics of the binary, see [e5e.WriteMetrics]. It's only valid in keepalive mode.
+// The metrics are returned as response of the execution.
+func (e *Engine) Metrics() Execution {
+	e.t.Helper()
+	return e.SendRaw([]byte("metrics"))
+}
+
+// Send sends the given request and checks that the binary answers with a result.
+func (e *Engine) Send(req *RequestBuilder) Execution {
+	e.t.Helper()
+	payload, err := req.Payload()
+	if err != nil {
+		e.t.Fatalf("e5etest: encoding request failed: %v", err)
+	}
+	x := e.SendRaw(payload)
+	if len(x.Violations) == 0 {
+		if _, err := x.Result(); err != nil {
+			e.addViolation(&x, fmt.Sprintf("response is not a valid result: %v", err))
+		}
+	}
+	return x
+}
+
+// SendRaw sends the given line to the binary and waits for the response.
+// The line must not contain a newline character.
+func (e *Engine) SendRaw(line []byte) Execution {
+	e.t.Helper()
+	x := Execution{Input: line}
+	if e.closed {
+		e.t.Fatalf("e5etest: engine is already closed")
+	}
+
+	start := time.Now()
+	if _, err := e.stdin.Write(append(line, '\n')); err != nil {
+		x.Violations = append(x.Violations, fmt.Sprintf("writing to stdin failed: %v", err))
+		e.recordViolations(x)
+		return x
+	}
+
+	deadline := time.Now().Add(e.opts.Timeout)
+	var stdoutFrame, stderrFrame []byte
+	var ok bool
+	if e.opts.KeepAlive {
+		if stdoutFrame, ok = e.stdout.next(e.daemonSequence, deadline); !ok {
+			x.Violations = append(x.Violations, e.missingFrame("stdout"))
+		}
+		if stderrFrame, ok = e.stderr.next(e.daemonSequence, deadline); !ok {
+			x.Violations = append(x.Violations, e.missingFrame("stderr"))
+		}
+	} else {
+		if stdoutFrame, ok = e.stdout.rest(deadline); !ok {
+			x.Violations = append(x.Violations, "binary did not exit after a single execution")
+		}
+		stderrFrame, _ = e.stderr.rest(deadline)
+	}
+	x.Duration = time.Since(start)
+	x.Stderr = string(stderrFrame)
+
+	if e.report.StartupDuration == 0 {
+		e.report.StartupDuration = time.Since(e.started)
+	}
+
+	generic, response, found := bytes.Cut(stdoutFrame, e.stdoutSequence)
+	switch {
+	case !found && len(x.Violations) == 0:
+		x.Violations = append(x.Violations, "response is not preceded by the stdout sequence")
+		x.Stdout = string(stdoutFrame)
+	case found:
+		x.Stdout, x.Response = string(generic), response
+		if bytes.Contains(response, e.stdoutSequence) {
+			x.Violations = append(x.Violations, "stdout sequence was written more than once")
+		}
+		// Responses to control messages, like the metrics, are not results and may span multiple lines.
+		if bytes.Contains(response, []byte("\n")) && !e.isControlMessage(line) {
+			x.Violations = append(x.Violations, "response spans multiple lines")
+		}
+	}
+
+	e.recordViolations(x)
+	e.report.Executions = append(e.report.Executions, x)
+	return x
+}
+
+// isControlMessage returns true if the line is a message to the runtime itself instead of an event.
+func (e *Engine) isControlMessage(line []byte) bool {
+	if !e.opts.KeepAlive {
+		return false
+	}
+	switch message := string(line); {
+	case message == "ping", message == "metrics", strings.HasPrefix(message, "profile "):
+		return true
+	}
+	return false
+}
+
+func (e *Engine) missingFrame(stream string) string {
+	select {
+	case <-e.exited:
+		return fmt.Sprintf("binary exited before terminating the execution on %s: %v", stream, e.cmd.ProcessState)
+	default:
+		return fmt.Sprintf("execution was not terminated on %s within %s", stream, e.opts.Timeout)
+	}
+}
+
+func (e *Engine) recordViolations(x Execution) {
+	e.report.Violations = append(e.report.Violations, x.Violations...)
+}
+
+// addViolation adds a violation to an execution that was already recorded.
+func (e *Engine) addViolation(x *Execution, violation string) {
+	x.Violations = append(x.Violations, violation)
+	e.report.Violations = append(e.report.Violations, violation)
+	e.report.Executions[len(e.report.Executions)-1] = *x
+}
+
+// Close closes stdin of the binary, waits for it to exit and returns the report of the whole run.
+// If the binary does not exit within the timeout, it is killed.
+func (e *Engine) Close() Report {
+	if e.closed {
+		return e.report
+	}
+	e.closed = true
+	_ = e.stdin.Close()
+
+	select {
+	case <-e.exited:
+	case <-time.After(e.opts.Timeout):
+		_ = e.cmd.Process.Kill()
+		<-e.exited
+		e.report.Violations = append(e.report.Violations, "binary did not exit after stdin was closed")
+	}
+
+	e.report.ExitCode = e.cmd.ProcessState.ExitCode()
+	if e.report.ExitCode != 0 {
+		e.report.Violations = append(e.report.Violations, fmt.Sprintf("binary exited with code %d", e.report.ExitCode))
+	}
+	if leftover := e.stdout.bytes(); len(leftover) > 0 {
+		e.report.Violations = append(e.report.Violations, fmt.Sprintf("unexpected output on stdout after the last execution: %q", leftover))
+	}
+	return e.report
+}
+
+// AssertConformance closes the engine and fails the test if any protocol violation was detected.
+func (e *Engine) AssertConformance() Report {
+	e.t.Helper()
+	report := e.Close()
+	for _, v := range report.Violations {
+		e.t.Errorf("e5etest: protocol violation: %s", v)
+	}
+	return report
+}
+
+// streamBuffer collects the output of a stream in the background and allows waiting for frames.
+type streamBuffer struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	closed  bool
+	changed chan struct{}
+	done    chan struct{}
+}
+
+func newStreamBuffer() *streamBuffer {
+	return &streamBuffer{changed: make(chan struct{}), done: make(chan struct{})}
+}
+
+func (s *streamBuffer) readFrom(r io.Reader) {
+	defer close(s.done)
+	chunk := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(chunk)
+		s.mu.Lock()
+		s.buf.Write(chunk[:n])
+		if err != nil {
+			s.closed = true
+		}
+		close(s.changed)
+		s.changed = make(chan struct{})
+		s.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// wait blocks until cond returns true or the deadline is exceeded. cond is called with the lock held.
+func (s *streamBuffer) wait(deadline time.Time, cond func() bool) bool {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		if cond() {
+			s.mu.Unlock()
+			return true
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return false
+		}
+	}
+}
+
+// next returns everything up to the given terminator and removes it, including the terminator, from the buffer.
+// It returns false if the stream got closed or the deadline exceeded before the terminator was written.
+func (s *streamBuffer) next(terminator []byte, deadline time.Time) ([]byte, bool) {
+	var (
+		frame []byte
+		found bool
+	)
+	s.wait(deadline, func() bool {
+		idx := bytes.Index(s.buf.Bytes(), terminator)
+		if idx < 0 {
+			return s.closed
+		}
+		frame = append([]byte(nil), s.buf.Next(idx)...)
+		s.buf.Next(len(terminator))
+		found = true
+		return true
+	})
+	return frame, found
+}
+
+// rest waits until the stream is closed and returns everything that's left.
+func (s *streamBuffer) rest(deadline time.Time) ([]byte, bool) {
+	var rest []byte
+	ok := s.wait(deadline, func() bool {
+		if !s.closed {
+			return false
+		}
+		rest = append([]byte(nil), s.buf.Next(s.buf.Len())...)
+		return true
+	})
+	return rest, ok
+}
+
+func (s *streamBuffer) bytes() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]byte(nil), s.buf.Bytes()...)
+}
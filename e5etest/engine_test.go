@@ -0,0 +1,60 @@
+package e5etest_test
+
+import (
+	"strings"
+	"testing"
+
+	"go.anx.io/e5e/v2/e5etest"
+)
+
+func TestEngine(t *testing.T) {
+	binary := e5etest.BuildBinary(t, "./testdata/function")
+
+	t.Run("keepalive", func(t *testing.T) {
+		engine := e5etest.StartEngine(t, binary, e5etest.EngineOptions{Entrypoint: "Sum", KeepAlive: true})
+		engine.Ping()
+		x := engine.Send(e5etest.NewRequest().WithData(SumData{A: 2, B: 3}))
+		engine.Ping()
+		engine.Send(e5etest.NewRequest().WithData(SumData{A: 1, B: 1}))
+		m := engine.Metrics()
+
+		Equal(t, "generic output", x.Stdout, "stdout does not match")
+		Equal(t, true, strings.HasPrefix(x.Stderr, "error output"), "stderr does not match: "+x.Stderr)
+		Equal(t, true, strings.Contains(x.Stderr, `"msg":"cold start"`), "cold start was not logged: "+x.Stderr)
+		var sum int
+		if err := x.DecodeResult(&sum); err != nil {
+			t.Fatalf("decoding result failed: %v", err)
+		}
+		Equal(t, 5, sum, "sum does not match")
+		Equal(t, true, strings.Contains(string(m.Response), `e5e_invocations_total{entrypoint="Sum"} 2`), "metrics do not match: "+string(m.Response))
+
+		report := engine.AssertConformance()
+		Equal(t, 5, len(report.Executions), "number of executions does not match")
+		Equal(t, 0, report.ExitCode, "exit code does not match")
+	})
+	t.Run("single execution", func(t *testing.T) {
+		engine := e5etest.StartEngine(t, binary, e5etest.EngineOptions{Entrypoint: "Sum"})
+		x := engine.Send(e5etest.NewRequest().WithData(SumData{A: 2, B: 3}).WithHeader("X-Request-Id", "test-request-id"))
+		Equal(t, `{"result":{"response_headers":{"X-Request-Id":"test-request-id"},"data":5}}`, string(x.Response), "response does not match")
+		engine.AssertConformance()
+	})
+	t.Run("violations are detected", func(t *testing.T) {
+		engine := e5etest.StartEngine(t, binary, e5etest.EngineOptions{Entrypoint: "Misbehave", KeepAlive: true})
+		x := engine.Send(e5etest.NewRequest())
+		Equal(t, 1, len(x.Violations), "number of violations does not match")
+		if !strings.Contains(x.Violations[0], "more than once") {
+			t.Errorf("unexpected violation: %s", x.Violations[0])
+		}
+		Equal(t, 1, len(engine.Close().Violations), "number of violations in report does not match")
+	})
+	t.Run("invalid entrypoint", func(t *testing.T) {
+		engine := e5etest.StartEngine(t, binary, e5etest.EngineOptions{Entrypoint: "DoesNotExist", KeepAlive: true})
+		x := engine.Ping()
+		if len(x.Violations) == 0 {
+			t.Errorf("expected violations, got none")
+		}
+		if report := engine.Close(); report.ExitCode == 0 {
+			t.Errorf("expected a non-zero exit code")
+		}
+	})
+}
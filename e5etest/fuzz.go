@@ -0,0 +1,182 @@
+package e5etest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"runtime/debug"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+// Fuzz turns the handler into a fuzz target. The corpus is seeded with the given requests and
+// all seeds added before by [AddSeedFile].
+//
+// Besides the mutations of the raw request envelope, the fuzzer mutates the event type and adds params,
+// request headers and base64 encoded files to the event. The handler is executed the same way the runtime does,
+// see [e5e.Invocation], and the following invariants are checked for every input:
+//
+//   - The handler must not panic.
+//   - If the handler returns no error, its result must be encodable.
+//   - The status of the result must be either 0 (the default) or a valid HTTP status code.
+//
+// Errors returned by the handler are fine, since most mutated inputs are invalid.
+//
+//	func FuzzSum(f *testing.F) {
+//		e5etest.AddSeedFile(f, "testdata/recording.jsonl")
+//		e5etest.Fuzz[SumData, any](f, Sum{}, e5etest.NewRequest().WithData(SumData{A: 2, B: 3}))
+//	}
+func Fuzz[T, TContext e5e.Data](f *testing.F, h e5e.Handler[T, TContext], seeds ...*RequestBuilder) {
+	f.Helper()
+	FuzzFactory(f, e5e.NewHandlerFactory(h), seeds...)
+}
+
+// FuzzFunc works like [Fuzz], but takes a handler function like [e5e.AddHandlerFunc].
+func FuzzFunc[T, TContext e5e.Data](f *testing.F, fn func(context.Context, e5e.Request[T, TContext]) (*e5e.Result, error), seeds ...*RequestBuilder) {
+	f.Helper()
+	FuzzFactory(f, e5e.NewHandlerFactory[T, TContext](e5e.HandlerFunc[T, TContext](fn)), seeds...)
+}
+
+// FuzzFactory works like [Fuzz], but takes a [e5e.HandlerFactory], e.g. a handler that's wrapped with middleware.
+func FuzzFactory(f *testing.F, factory e5e.HandlerFactory, seeds ...*RequestBuilder) {
+	f.Helper()
+	for _, seed := range seeds {
+		payload, err := seed.Payload()
+		if err != nil {
+			f.Fatalf("e5etest: encoding seed failed: %v", err)
+		}
+		addSeed(f, payload)
+	}
+
+	factory = e5e.Invocation(entrypoint, factory)
+	f.Fuzz(func(t *testing.T, payload []byte, eventType, key, value string, file []byte) {
+		payload = mutateEnvelope(payload, eventType, key, value, file)
+		checkInvariants(t, factory, payload)
+	})
+}
+
+// AddSeedFile adds the requests of the given file to the corpus of the fuzz target.
+// The file contains either a single request envelope, like it's sent by E5E,
+// or a recording with one [e5e.Recording] per line, see [e5e.Replay].
+// It must be called before [Fuzz].
+func AddSeedFile(f *testing.F, path string) {
+	f.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		f.Fatalf("e5etest: reading seed file failed: %v", err)
+	}
+
+	var envelope struct {
+		Event json.RawMessage `json:"event"`
+	}
+	if json.Unmarshal(content, &envelope) == nil && envelope.Event != nil {
+		addSeed(f, content)
+		return
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer([]byte{}, 1024*1024*1024) // 1 GiB
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var rec e5e.Recording
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil || rec.Request == nil {
+			f.Fatalf("e5etest: %s:%d is neither a request nor a recording", path, line)
+		}
+		addSeed(f, rec.Request)
+	}
+	if err := scanner.Err(); err != nil {
+		f.Fatalf("e5etest: reading seed file failed: %v", err)
+	}
+}
+
+// addSeed adds the payload without any additional mutations to the corpus.
+func addSeed(f *testing.F, payload []byte) {
+	f.Add(payload, "", "", "", []byte(nil))
+}
+
+// mutateEnvelope applies the structured mutations to the request envelope.
+// If the payload is no JSON object, it's returned unchanged, so the raw decoding is fuzzed as well.
+func mutateEnvelope(payload []byte, eventType, key, value string, file []byte) []byte {
+	var envelope map[string]any
+	if err := json.Unmarshal(payload, &envelope); err != nil || envelope == nil {
+		return payload
+	}
+	event, _ := envelope["event"].(map[string]any)
+	if event == nil {
+		event = make(map[string]any)
+		envelope["event"] = event
+	}
+
+	if eventType != "" {
+		event["type"] = eventType
+	}
+	if key != "" {
+		params, _ := event["params"].(map[string]any)
+		if params == nil {
+			params = make(map[string]any)
+			event["params"] = params
+		}
+		params[key] = []any{value}
+
+		headers, _ := event["request_headers"].(map[string]any)
+		if headers == nil {
+			headers = make(map[string]any)
+			event["request_headers"] = headers
+		}
+		headers[key] = value
+	}
+	if file != nil {
+		encoded := map[string]any{
+			"binary": base64.StdEncoding.EncodeToString(file),
+			"type":   "binary",
+			"size":   len(file),
+			"name":   key,
+		}
+		switch event["type"] {
+		case string(e5e.EventDataTypeBinary):
+			event["data"] = encoded
+		case string(e5e.EventDataTypeMixed):
+			switch data := event["data"].(type) {
+			case []any:
+				event["data"] = append(data, encoded)
+			case map[string]any:
+				data[key] = []any{encoded}
+			default:
+				event["data"] = []any{encoded}
+			}
+		}
+	}
+
+	mutated, err := json.Marshal(envelope)
+	if err != nil {
+		return payload
+	}
+	return mutated
+}
+
+// checkInvariants executes the handler with the payload and fails the test if an invariant does not hold.
+func checkInvariants(t testing.TB, factory e5e.HandlerFactory, payload []byte) {
+	t.Helper()
+	defer func() {
+		if v := recover(); v != nil {
+			t.Fatalf("e5etest: handler panicked for payload %s: %v\n%s", payload, v, debug.Stack())
+		}
+	}()
+
+	res, err := factory.Execute(context.Background(), payload)
+	if err != nil {
+		return
+	}
+	if _, err := e5e.MarshalResult(res); err != nil {
+		t.Fatalf("e5etest: encoding the result failed for payload %s: %v", payload, err)
+	}
+	if res != nil && res.Status != 0 && (res.Status < 100 || res.Status > 599) {
+		t.Fatalf("e5etest: invalid status %d for payload %s", res.Status, payload)
+	}
+}
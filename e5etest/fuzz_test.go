@@ -0,0 +1,27 @@
+package e5etest_test
+
+import (
+	"context"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+	"go.anx.io/e5e/v2/e5etest"
+)
+
+func FuzzSum(f *testing.F) {
+	e5etest.FuzzFunc(f, Sum,
+		e5etest.NewRequest().WithData(SumData{A: 2, B: 3}).WithContextData(AuthContext{AuthKey: "secret"}),
+		e5etest.NewRequest().WithData(SumData{A: -1}).WithParam("mode", "a"),
+	)
+}
+
+func FuzzFiles(f *testing.F) {
+	e5etest.AddSeedFile(f, "../testdata/binary_request_with_multiple_files.json")
+	e5etest.FuzzFunc(f, func(ctx context.Context, r e5e.Request[[]e5e.File, any]) (*e5e.Result, error) {
+		var size int64
+		for _, file := range r.Data() {
+			size += file.SizeInBytes
+		}
+		return &e5e.Result{Data: size, ResponseHeaders: map[string]string{"X-Files": r.Event.Header("X-Files")}}, nil
+	})
+}
@@ -0,0 +1,190 @@
+package e5etest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+// update is prefixed with the package name, since registering "-update" panics if the tests define it as well,
+// which is common for golden files.
+var update = flag.Bool("e5etest.update", false, "update the golden files compared by e5etest.Golden")
+
+// goldenContext is the number of unchanged lines printed around every change in the diff of [Golden].
+const goldenContext = 3
+
+// Golden compares the result, encoded exactly as the runtime writes it, with the golden file
+// "testdata/<name>.golden.json" relative to the package directory of the test.
+// If they differ, the test fails with a line-based diff.
+//
+// The result is stored as indented JSON with sorted object keys, so golden files are stable and easy to review.
+// Files are contained as base64, just like E5E receives them. The "X-Request-Id" response header is left out,
+// since the request ID echoed by [Invoke] is generated for every invocation.
+//
+// Running the tests with the "-e5etest.update" flag, e.g. "go test . -e5etest.update", writes the golden files instead.
+func Golden(t testing.TB, name string, res *e5e.Result) {
+	t.Helper()
+
+	actual, err := goldenEncode(res)
+	if err != nil {
+		t.Fatalf("encoding result for golden file %q failed: %v", name, err)
+	}
+
+	path := filepath.Join("testdata", filepath.FromSlash(name)+".golden.json")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("creating directory for golden file failed: %v", err)
+		}
+		if err := os.WriteFile(path, actual, 0o644); err != nil {
+			t.Fatalf("writing golden file failed: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("golden file %s does not exist, run the tests with -e5etest.update to create it", path)
+	} else if err != nil {
+		t.Fatalf("reading golden file failed: %v", err)
+	}
+	// Ignore line endings changed by git on checkout.
+	expected = bytes.ReplaceAll(expected, []byte("\r\n"), []byte("\n"))
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("result does not match golden file %s, run the tests with -e5etest.update to accept the changes:\n%s",
+			path, lineDiff(string(expected), string(actual)))
+	}
+}
+
+// AssertGolden checks that no error occurred and compares the result with a golden file, see [Golden].
+func (r *Response) AssertGolden(name string) *Response {
+	r.t.Helper()
+	r.AssertNoError()
+	Golden(r.t, name, r.Result)
+	return r
+}
+
+// goldenEncode encodes the result like the runtime does and formats it with sorted keys and indentation.
+func goldenEncode(res *e5e.Result) ([]byte, error) {
+	raw, err := e5e.MarshalResult(res)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decoding and encoding again sorts the keys of all objects. Numbers are kept as they are.
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	withoutRequestID(v)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// withoutRequestID removes the echoed request ID from the decoded response.
+func withoutRequestID(response any) {
+	decoded, _ := response.(map[string]any)
+	result, _ := decoded["result"].(map[string]any)
+	headers, ok := result["response_headers"].(map[string]any)
+	if !ok {
+		return
+	}
+	for k := range headers {
+		if strings.EqualFold(k, "X-Request-Id") {
+			delete(headers, k)
+		}
+	}
+	if len(headers) == 0 {
+		delete(result, "response_headers")
+	}
+}
+
+// lineDiff returns the differences between the two texts, with removed lines prefixed by "-",
+// added lines prefixed by "+" and some unchanged lines around them as context.
+func lineDiff(expected, actual string) string {
+	a := strings.Split(strings.TrimSuffix(expected, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(actual, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, line{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, line{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, line{'+', b[j]})
+			j++
+		}
+	}
+
+	// Only print unchanged lines that are close to a change.
+	visible := make([]bool, len(lines))
+	for n, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for k := n - goldenContext; k <= n+goldenContext; k++ {
+			if k >= 0 && k < len(lines) {
+				visible[k] = true
+			}
+		}
+	}
+
+	var sb strings.Builder
+	skipped := false
+	for n, l := range lines {
+		if !visible[n] {
+			skipped = true
+			continue
+		}
+		if skipped && sb.Len() > 0 {
+			sb.WriteString("  ...\n")
+		}
+		skipped = false
+		_, _ = fmt.Fprintf(&sb, "%c %s\n", l.op, l.text)
+	}
+	return sb.String()
+}
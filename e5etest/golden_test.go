@@ -0,0 +1,41 @@
+package e5etest_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+	"go.anx.io/e5e/v2/e5etest"
+)
+
+func TestGolden(t *testing.T) {
+	t.Parallel()
+
+	t.Run("matches", func(t *testing.T) {
+		t.Parallel()
+		req := e5etest.NewRequest().WithData(SumData{A: 2, B: 3}).WithParam("mode", "a").WithContextData(AuthContext{AuthKey: "secret"})
+		e5etest.InvokeFunc(t, Sum, req).AssertGolden("sum")
+
+		var file e5e.File
+		_, _ = file.Write([]byte("hello world"))
+		e5etest.Golden(t, "file", &e5e.Result{Data: file, ResponseHeaders: map[string]string{"B": "2", "A": "1"}})
+	})
+	t.Run("reports differences", func(t *testing.T) {
+		t.Parallel()
+		tb := &recordingTB{TB: t}
+		failures := tb.run(func() {
+			req := e5etest.NewRequest().WithData(SumData{A: 2, B: 4}).WithParam("mode", "a").WithContextData(AuthContext{AuthKey: "secret"})
+			e5etest.InvokeFunc(tb, Sum, req).AssertGolden("sum")
+		})
+		Equal(t, 1, len(failures), fmt.Sprintf("number of failures does not match: %q", failures))
+		Equal(t, true, strings.Contains(failures[0], "-       \"sum\": 5\n+       \"sum\": 6\n"), "diff is missing: "+failures[0])
+	})
+	t.Run("missing golden file", func(t *testing.T) {
+		t.Parallel()
+		tb := &recordingTB{TB: t}
+		failures := tb.run(func() { e5etest.Golden(tb, "does-not-exist", nil) })
+		Equal(t, 1, len(failures), fmt.Sprintf("number of failures does not match: %q", failures))
+		Equal(t, true, strings.Contains(failures[0], "run the tests with -e5etest.update"), "hint is missing: "+failures[0])
+	})
+}
@@ -0,0 +1,163 @@
+package e5etest
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+// decodedResult is the result as E5E decodes it from the output of the runtime.
+type decodedResult struct {
+	Status          int                `json:"status"`
+	ResponseHeaders map[string]string  `json:"response_headers"`
+	Data            json.RawMessage    `json:"data"`
+	Type            e5e.ResultDataType `json:"type"`
+}
+
+// Response contains the outcome of an invocation, see [Invoke].
+//
+// The assertion methods fail the test if the assertion does not hold and return the response,
+// so they can be chained.
+type Response struct {
+	t testing.TB
+
+	// The result returned by the handler.
+	Result *e5e.Result
+
+	// The error returned by the handler or by encoding its result.
+	Err error
+
+	// The request ID of the invocation, see [e5e.RequestID].
+	RequestID string
+
+	// The encoded result, as written by the runtime.
+	Raw []byte
+
+	// The result as decoded by E5E, nil if the handler returned no result.
+	decoded *decodedResult
+}
+
+// AssertNoError checks that neither the handler nor the encoding of its result failed.
+func (r *Response) AssertNoError() *Response {
+	r.t.Helper()
+	if r.Err != nil {
+		r.t.Fatalf("expected no error, got: %v", r.Err)
+	}
+	return r
+}
+
+// AssertError checks that the handler or the encoding of its result failed
+// and that the error message contains the given text.
+func (r *Response) AssertError(contains string) *Response {
+	r.t.Helper()
+	if r.Err == nil {
+		r.t.Fatalf("expected an error containing %q, got none", contains)
+	}
+	if !strings.Contains(r.Err.Error(), contains) {
+		r.t.Fatalf("expected an error containing %q, got: %v", contains, r.Err)
+	}
+	return r
+}
+
+// result returns the decoded result and fails the test if there's none.
+func (r *Response) result() *decodedResult {
+	r.t.Helper()
+	r.AssertNoError()
+	if r.decoded == nil {
+		r.t.Fatalf("expected a result, got none")
+	}
+	return r.decoded
+}
+
+// AssertStatus checks the status of the result.
+func (r *Response) AssertStatus(status int) *Response {
+	r.t.Helper()
+	if actual := r.result().Status; actual != status {
+		r.t.Errorf("status does not match:\n\tgot:\t%d\n\twanted:\t%d", actual, status)
+	}
+	return r
+}
+
+// AssertHeader checks the value of the given response header. The header name is case-insensitive.
+func (r *Response) AssertHeader(key, value string) *Response {
+	r.t.Helper()
+	var actual string
+	for k, v := range r.result().ResponseHeaders {
+		if strings.EqualFold(k, key) {
+			actual = v
+		}
+	}
+	if actual != value {
+		r.t.Errorf("response header %q does not match:\n\tgot:\t%q\n\twanted:\t%q", key, actual, value)
+	}
+	return r
+}
+
+// AssertType checks the data type of the result.
+func (r *Response) AssertType(t e5e.ResultDataType) *Response {
+	r.t.Helper()
+	if actual := r.result().Type; actual != t {
+		r.t.Errorf("result type does not match:\n\tgot:\t%q\n\twanted:\t%q", actual, t)
+	}
+	return r
+}
+
+// DecodeData decodes the JSON encoded data of the result into v.
+func (r *Response) DecodeData(v any) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.result().Data, v); err != nil {
+		r.t.Fatalf("decoding result data failed: %v", err)
+	}
+	return r
+}
+
+// AssertData checks that the data of the result equals the expected value, after both have been
+// encoded to JSON and decoded again. Therefore, the result data can be compared with any structurally
+// equal value, e.g. a struct with a map.
+func (r *Response) AssertData(expected any) *Response {
+	r.t.Helper()
+	encoded, err := json.Marshal(expected)
+	if err != nil {
+		r.t.Fatalf("encoding expected data failed: %v", err)
+	}
+	return r.AssertJSON(string(encoded))
+}
+
+// AssertJSON checks that the data of the result is semantically equal to the given JSON document.
+func (r *Response) AssertJSON(expected string) *Response {
+	r.t.Helper()
+	var want, got any
+	if err := json.Unmarshal([]byte(expected), &want); err != nil {
+		r.t.Fatalf("decoding expected JSON failed: %v", err)
+	}
+	data := r.result().Data
+	if err := json.Unmarshal(data, &got); err != nil {
+		r.t.Fatalf("decoding result data failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		r.t.Errorf("result data does not match:\n\tgot:\t%s\n\twanted:\t%s", data, expected)
+	}
+	return r
+}
+
+// File decodes the data of the result into a file.
+func (r *Response) File() e5e.File {
+	r.t.Helper()
+	var f e5e.File
+	r.DecodeData(&f)
+	return f
+}
+
+// AssertFile checks that the result is a binary result and contains a file with the given content.
+func (r *Response) AssertFile(content []byte) *Response {
+	r.t.Helper()
+	r.AssertType(e5e.ResultDataTypeBinary)
+	if actual := r.File().Bytes(); !bytes.Equal(actual, content) {
+		r.t.Errorf("file content does not match:\n\tgot:\t%q\n\twanted:\t%q", actual, content)
+	}
+	return r
+}
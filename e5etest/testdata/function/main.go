@@ -0,0 +1,30 @@
+// Command function is a small e5e function that is used to test the conformance engine.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.anx.io/e5e/v2"
+)
+
+type SumData struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+func main() {
+	e5e.AddHandlerFunc("Sum", func(ctx context.Context, r e5e.Request[SumData, any]) (*e5e.Result, error) {
+		fmt.Print("generic output")
+		_, _ = fmt.Fprint(os.Stderr, "error output")
+		return &e5e.Result{Data: r.Data().A + r.Data().B}, nil
+	})
+	e5e.AddHandlerFunc("Misbehave", func(ctx context.Context, r e5e.Request[any, any]) (*e5e.Result, error) {
+		// Writing the stdout sequence from within the handler breaks the protocol.
+		fmt.Print(strings.ReplaceAll(os.Args[2], "\\0", "\x00"))
+		return &e5e.Result{}, nil
+	})
+	e5e.Start(context.Background())
+}
@@ -1,6 +1,12 @@
 package e5e
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMemoryLimitExceeded is the cause (see [context.Cause]) of cancelled invocations that exceeded the soft memory limit.
+var ErrMemoryLimitExceeded = errors.New("memory limit exceeded")
 
 // InvalidEntrypointError is returned if the given entrypoint did not get registered before invoking [Start].
 type InvalidEntrypointError struct{ Entrypoint string }
@@ -8,3 +14,17 @@ type InvalidEntrypointError struct{ Entrypoint string }
 func (e InvalidEntrypointError) Error() string {
 	return fmt.Sprintf("entrypoint %q does not exist", e.Entrypoint)
 }
+
+// UnsupportedArchiveError is returned by [OpenArchive] if the given file is neither a zip nor a gzip-compressed tar archive.
+type UnsupportedArchiveError struct{ ContentType string }
+
+func (e UnsupportedArchiveError) Error() string {
+	return fmt.Sprintf("unsupported archive format (content type %q)", e.ContentType)
+}
+
+// ArchiveTooLargeError is returned by [OpenArchive] if the unpacked files exceed [ArchiveOptions.MaxUnpackedSize].
+type ArchiveTooLargeError struct{ MaxSize int64 }
+
+func (e ArchiveTooLargeError) Error() string {
+	return fmt.Sprintf("unpacked archive exceeds %d bytes", e.MaxSize)
+}
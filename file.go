@@ -1,6 +1,7 @@
 package e5e
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -51,6 +52,14 @@ func (f File) Bytes() []byte { return f.content }
 // Read implements io.Reader.
 func (f File) Read(p []byte) (n int, err error) { return copy(p, f.content), io.EOF }
 
+// ReadAt implements io.ReaderAt.
+func (f File) ReadAt(p []byte, off int64) (n int, err error) {
+	return bytes.NewReader(f.content).ReadAt(p, off)
+}
+
+// NewReader returns a seekable reader for the contents of this file.
+func (f File) NewReader() *bytes.Reader { return bytes.NewReader(f.content) }
+
 // Write implements io.Writer.
 // It further sets the content type to the output of [http.DetectContentType],
 // the file size and the charset, if none of those properties have been set before.
@@ -123,6 +132,7 @@ func (f *File) UnmarshalJSON(data []byte) error {
 
 // compile-time check for certain interfaces
 var _ io.Reader = File{}
+var _ io.ReaderAt = File{}
 var _ io.Writer = &File{}
 var _ json.Unmarshaler = &File{}
 var _ json.Marshaler = File{}
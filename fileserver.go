@@ -0,0 +1,167 @@
+package e5e
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// FileServerHandler is a [Handler] that serves static files from a file system, e.g. an [embed.FS].
+// Use [FileServer] to create one with sensible defaults.
+//
+// Files are returned as [ResultDataTypeBinary] results, with the content type determined by the file extension.
+// Each result has an ETag header, so conditional requests are answered with "304 Not Modified",
+// and Range requests are honoured as described in [RangeResult].
+type FileServerHandler struct {
+	// The file system the files are served from.
+	FS fs.FS
+
+	// The name of the GET parameter that contains the requested path.
+	PathParam string
+
+	// The name of the request header that contains the requested path.
+	// If set and sent with the request, it takes precedence over PathParam.
+	PathHeader string
+
+	// The file that's served if a directory is requested.
+	IndexFile string
+
+	// If set to true, the IndexFile of the root directory is served for paths that do not exist,
+	// instead of returning status 404. This is required for single page applications with client-side routing.
+	SPAFallback bool
+
+	// The value of the Cache-Control response header. If empty, the header is not set.
+	CacheControl string
+}
+
+// FileServer returns a handler that serves the files of fsys.
+// The requested path is read from the "path" GET parameter, directories are served by their "index.html" file.
+// The defaults can be changed on the returned handler before registering it:
+//
+//	//go:embed static
+//	var static embed.FS
+//
+//	func main() {
+//		assets, _ := fs.Sub(static, "static")
+//		fileServer := e5e.FileServer(assets)
+//		fileServer.SPAFallback = true
+//		e5e.AddHandlerFunc("Assets", fileServer.Handle)
+//		e5e.Start(context.Background())
+//	}
+func FileServer(fsys fs.FS) *FileServerHandler {
+	return &FileServerHandler{
+		FS:           fsys,
+		PathParam:    "path",
+		IndexFile:    "index.html",
+		CacheControl: "no-cache",
+	}
+}
+
+// Handle implements [Handler].
+func (h *FileServerHandler) Handle(_ context.Context, r Request[json.RawMessage, json.RawMessage]) (*Result, error) {
+	name, ok := h.requestedPath(r.Event)
+	if !ok {
+		return &Result{Status: 400, Type: ResultDataTypeText, Data: "invalid path"}, nil
+	}
+
+	file, info, err := h.open(name)
+	if errors.Is(err, fs.ErrNotExist) && h.SPAFallback {
+		file, info, err = h.open(".")
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Result{Status: 404, Type: ResultDataTypeText, Data: "file not found"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]string)
+	if h.CacheControl != "" {
+		headers["Cache-Control"] = h.CacheControl
+	}
+	if !info.ModTime().IsZero() {
+		headers["Last-Modified"] = info.ModTime().UTC().Format(http.TimeFormat)
+	}
+
+	res, err := evaluateConditional(r.Event.RequestHeaders, &Result{
+		Status:          200,
+		ResponseHeaders: headers,
+		Type:            ResultDataTypeBinary,
+		Data:            file,
+	})
+	if err != nil || res.Status == 304 {
+		return res, err
+	}
+
+	if rangeHeader := r.Event.Header("Range"); rangeHeader != "" {
+		partial := RangeResult(file, rangeHeader)
+		for k, v := range res.ResponseHeaders {
+			partial.ResponseHeaders[k] = v
+		}
+		return partial, nil
+	}
+	res.ResponseHeaders["Accept-Ranges"] = "bytes"
+	return res, nil
+}
+
+// requestedPath returns the cleaned path of the requested file, relative to the root of the file system.
+// It returns false if the path tries to escape the root.
+func (h *FileServerHandler) requestedPath(e Event[json.RawMessage]) (string, bool) {
+	var name string
+	if h.PathHeader != "" {
+		name = e.Header(h.PathHeader)
+	}
+	if name == "" && len(e.Params[h.PathParam]) > 0 {
+		name = e.Params[h.PathParam][0]
+	}
+
+	for _, segment := range strings.Split(strings.ReplaceAll(name, "\\", "/"), "/") {
+		if segment == ".." {
+			return "", false
+		}
+	}
+
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "."
+	}
+	return name, fs.ValidPath(name)
+}
+
+// open reads the file with the given name. If it's a directory, the index file inside of it is read instead.
+func (h *FileServerHandler) open(name string) (File, fs.FileInfo, error) {
+	info, err := fs.Stat(h.FS, name)
+	if err != nil {
+		return File{}, nil, err
+	}
+	if info.IsDir() {
+		if h.IndexFile == "" {
+			return File{}, nil, fs.ErrNotExist
+		}
+		name = path.Join(name, h.IndexFile)
+		if info, err = fs.Stat(h.FS, name); err != nil {
+			return File{}, nil, err
+		}
+		if info.IsDir() {
+			return File{}, nil, fs.ErrNotExist
+		}
+	}
+
+	content, err := fs.ReadFile(h.FS, name)
+	if err != nil {
+		return File{}, nil, fmt.Errorf("reading file %q: %w", name, err)
+	}
+
+	file := File{Name: path.Base(name)}
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		file.ContentType, _, _ = strings.Cut(contentType, ";")
+	}
+	_, _ = file.Write(content)
+	return file, info, nil
+}
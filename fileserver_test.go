@@ -0,0 +1,95 @@
+package e5e_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestFileServer(t *testing.T) {
+	t.Parallel()
+
+	modTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("<h1>Home</h1>"), ModTime: modTime},
+		"docs/index.html": {Data: []byte("<h1>Docs</h1>")},
+		"css/style.css":   {Data: []byte("body { color: red; }")},
+		"data.json":       {Data: []byte(`{"a":1}`)},
+		"empty/.keep":     {},
+	}
+
+	request := func(path string, headers map[string]string) e5e.Request[json.RawMessage, json.RawMessage] {
+		return e5e.Request[json.RawMessage, json.RawMessage]{
+			Event: e5e.Event[json.RawMessage]{
+				Params:         map[string][]string{"path": {path}},
+				RequestHeaders: headers,
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		headers     map[string]string
+		status      int
+		content     string
+		contentType string
+	}{
+		{name: "root index", path: "/", status: 200, content: "<h1>Home</h1>", contentType: "text/html"},
+		{name: "directory index", path: "docs/", status: 200, content: "<h1>Docs</h1>", contentType: "text/html"},
+		{name: "file by extension", path: "/css/style.css", status: 200, content: "body { color: red; }", contentType: "text/css"},
+		{name: "json", path: "data.json", status: 200, content: `{"a":1}`, contentType: "application/json"},
+		{name: "not found", path: "missing.txt", status: 404},
+		{name: "directory without index", path: "empty", status: 404},
+		{name: "traversal", path: "../secret", status: 400},
+		{name: "encoded traversal", path: "css/..\\..\\secret", status: 400},
+		{name: "range", path: "data.json", headers: map[string]string{"Range": "bytes=0-1"}, status: 206, content: `{"`, contentType: "application/json"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			res, err := e5e.FileServer(fsys).Handle(context.Background(), request(tt.path, tt.headers))
+			if err != nil {
+				t.Fatalf("handling failed: %v", err)
+			}
+
+			Equal(t, tt.status, res.Status, "status does not match")
+			if tt.status >= 400 {
+				return
+			}
+			file := res.Data.(e5e.File)
+			Equal(t, tt.content, string(file.Bytes()), "content does not match")
+			Equal(t, tt.contentType, file.ContentType, "content type does not match")
+			Equal(t, "no-cache", res.ResponseHeaders["Cache-Control"], "Cache-Control does not match")
+			if res.ResponseHeaders["ETag"] == "" {
+				t.Errorf("ETag header is missing")
+			}
+		})
+	}
+
+	t.Run("conditional request", func(t *testing.T) {
+		t.Parallel()
+		server := e5e.FileServer(fsys)
+		res, _ := server.Handle(context.Background(), request("/", nil))
+		Equal(t, "Mon, 01 Jan 2024 12:00:00 GMT", res.ResponseHeaders["Last-Modified"], "Last-Modified does not match")
+
+		res, _ = server.Handle(context.Background(), request("/", map[string]string{"If-None-Match": res.ResponseHeaders["ETag"]}))
+		Equal(t, 304, res.Status, "status does not match")
+		DeepEqual(t, nil, res.Data, "data is not empty")
+	})
+	t.Run("path header and SPA fallback", func(t *testing.T) {
+		t.Parallel()
+		server := e5e.FileServer(fsys)
+		server.PathHeader = "X-Path"
+		server.SPAFallback = true
+
+		res, _ := server.Handle(context.Background(), request("data.json", map[string]string{"x-path": "/app/settings"}))
+		Equal(t, 200, res.Status, "status does not match")
+		Equal(t, "<h1>Home</h1>", string(res.Data.(e5e.File).Bytes()), "fallback content does not match")
+	})
+}
@@ -0,0 +1,263 @@
+package e5e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// HTTPHandlerAdapter is a [Handler] that runs an [http.Handler], e.g. an existing REST service.
+// Use [FromHTTPHandler] to create one with sensible defaults.
+//
+// For each event, an [http.Request] is built:
+//
+//   - The method and the path are read from the event as described by the [RouteSource].
+//   - The remaining params become the query, the request headers become the HTTP headers.
+//   - [EventDataTypeObject] data is sent as JSON, [EventDataTypeText] data as plain text,
+//     [EventDataTypeBinary] data with the content type of the file and [EventDataTypeMixed] data as multipart form.
+//
+// The response of the handler is converted into a [Result]: JSON bodies become [ResultDataTypeObject],
+// UTF-8 text bodies become [ResultDataTypeText] and all other bodies become [ResultDataTypeBinary].
+// Multiple values of a response header are joined with a comma.
+type HTTPHandlerAdapter struct {
+	RouteSource
+
+	// The handler that handles the requests.
+	Handler http.Handler
+
+	// The host of the built requests.
+	// The Host request header of the event takes precedence.
+	Host string
+}
+
+// FromHTTPHandler returns a handler that runs h, reading the method and the path from the event
+// as described by [DefaultRouteSource]. The defaults can be changed on the returned handler before registering it:
+//
+//	func main() {
+//		router := http.NewServeMux()
+//		router.HandleFunc("/hello", hello)
+//		e5e.AddHandlerFunc("API", e5e.FromHTTPHandler(router).Handle)
+//		e5e.Start(context.Background())
+//	}
+func FromHTTPHandler(h http.Handler) *HTTPHandlerAdapter {
+	return &HTTPHandlerAdapter{
+		RouteSource: DefaultRouteSource,
+		Handler:     h,
+		Host:        "localhost",
+	}
+}
+
+// Handle implements [Handler].
+func (a *HTTPHandlerAdapter) Handle(ctx context.Context, r Request[json.RawMessage, json.RawMessage]) (*Result, error) {
+	req, err := a.httpRequest(ctx, r.Event)
+	if err != nil {
+		return &Result{Status: 400, Type: ResultDataTypeText, Data: err.Error()}, nil
+	}
+
+	rec := &responseRecorder{header: make(http.Header)}
+	a.Handler.ServeHTTP(rec, req)
+	return rec.result(), nil
+}
+
+// httpRequest builds the HTTP request for the event.
+func (a *HTTPHandlerAdapter) httpRequest(ctx context.Context, e Event[json.RawMessage]) (*http.Request, error) {
+	method, p := a.route(e)
+	u, err := url.ParseRequestURI(p)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q", p)
+	}
+	query := u.Query()
+	for k, v := range a.params(e) {
+		query[k] = append(query[k], v...)
+	}
+	u.RawQuery = query.Encode()
+	u.Scheme = "http"
+	u.Host = a.Host
+	if host := e.Header("Host"); host != "" {
+		u.Host = host
+	}
+
+	body, contentType, err := httpRequestBody(e)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.RequestURI = u.RequestURI()
+	for k, v := range e.RequestHeaders {
+		req.Header.Set(k, v)
+	}
+	if contentType != "" && (req.Header.Get("Content-Type") == "" || e.Type == EventDataTypeMixed) {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Del("Content-Length")
+	return req, nil
+}
+
+// httpRequestBody encodes the event data as HTTP request body and returns it with its content type.
+func httpRequestBody(e Event[json.RawMessage]) ([]byte, string, error) {
+	if len(e.Data) == 0 || string(e.Data) == "null" {
+		return nil, "", nil
+	}
+
+	switch e.Type {
+	case EventDataTypeText:
+		var text string
+		if err := json.Unmarshal(e.Data, &text); err != nil {
+			return nil, "", fmt.Errorf("decoding text data: %w", err)
+		}
+		return []byte(text), "text/plain; charset=utf-8", nil
+	case EventDataTypeBinary:
+		var file File
+		if err := json.Unmarshal(e.Data, &file); err != nil {
+			return nil, "", fmt.Errorf("decoding binary data: %w", err)
+		}
+		return file.content, file.ContentType, nil
+	case EventDataTypeMixed:
+		return multipartBody(e.Data)
+	default:
+		return e.Data, "application/json", nil
+	}
+}
+
+// multipartBody encodes mixed event data as multipart form.
+// The data is either an object of form fields, or an array of files that are sent as "file" fields.
+func multipartBody(data json.RawMessage) ([]byte, string, error) {
+	var fields map[string][]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		var files []json.RawMessage
+		if err := json.Unmarshal(data, &files); err != nil {
+			return nil, "", fmt.Errorf("decoding mixed data: %w", err)
+		}
+		fields = map[string][]json.RawMessage{"file": files}
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, name := range sortedKeys(fields) {
+		for _, value := range fields[name] {
+			if err := writeMultipartValue(w, name, value); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+func writeMultipartValue(w *multipart.Writer, name string, value json.RawMessage) error {
+	var probe struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(value, &probe) != nil || probe.Type != "binary" {
+		var text string
+		if err := json.Unmarshal(value, &text); err != nil {
+			text = string(value)
+		}
+		return w.WriteField(name, text)
+	}
+
+	var file File
+	if err := json.Unmarshal(value, &file); err != nil {
+		return fmt.Errorf("decoding file of form field %q: %w", name, err)
+	}
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": name, "filename": file.Name}))
+	header.Set("Content-Type", contentType)
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(file.content)
+	return err
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// responseRecorder is an [http.ResponseWriter] that keeps the response in memory.
+type responseRecorder struct {
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+}
+
+func (r *responseRecorder) Header() http.Header { return r.header }
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.status, r.wroteHeader = status, true
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(p)
+}
+
+// Flush implements [http.Flusher], it's a no-op since the response is sent at once.
+func (r *responseRecorder) Flush() {}
+
+// result converts the recorded response into a result.
+func (r *responseRecorder) result() *Result {
+	status := r.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	res := &Result{Status: status, ResponseHeaders: make(map[string]string, len(r.header))}
+	for k, v := range r.header {
+		if k != "Content-Length" {
+			res.ResponseHeaders[k] = strings.Join(v, ", ")
+		}
+	}
+
+	body := r.body.Bytes()
+	if len(body) == 0 {
+		return res
+	}
+	contentType := r.header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	charset := strings.ToLower(params["charset"])
+	switch {
+	case (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) && json.Valid(body):
+		res.Type, res.Data = ResultDataTypeObject, json.RawMessage(body)
+	case strings.HasPrefix(mediaType, "text/") && (charset == "" || charset == "utf-8") && utf8.Valid(body):
+		res.Type, res.Data = ResultDataTypeText, string(body)
+	default:
+		file := File{ContentType: mediaType, Charset: params["charset"]}
+		_, _ = file.Write(body)
+		res.Type, res.Data = ResultDataTypeBinary, file
+	}
+	return res
+}
+
+var _ http.ResponseWriter = &responseRecorder{}
+var _ http.Flusher = &responseRecorder{}
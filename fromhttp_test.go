@@ -0,0 +1,129 @@
+package e5e_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestFromHTTPHandler(t *testing.T) {
+	t.Parallel()
+
+	router := http.NewServeMux()
+	router.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-Values", "a")
+		w.Header().Add("X-Values", "b")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"method":       r.Method,
+			"path":         r.URL.Path,
+			"query":        r.URL.RawQuery,
+			"content_type": r.Header.Get("Content-Type"),
+			"body":         string(body),
+		})
+	})
+	router.HandleFunc("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprint(w, "hello")
+	})
+	router.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = fmt.Fprintf(w, "%s=%s:%s", r.FormValue("title"), header.Filename, content)
+	})
+
+	var file e5e.File
+	_, _ = file.Write([]byte("hello world"))
+	file.Name = "hello.txt"
+	rawFile, _ := json.Marshal(file)
+
+	tests := []struct {
+		name    string
+		event   e5e.Event[json.RawMessage]
+		status  int
+		typ     e5e.ResultDataType
+		data    string
+		headers map[string]string
+	}{
+		{
+			name: "object with method and path",
+			event: e5e.Event[json.RawMessage]{
+				Type:           e5e.EventDataTypeObject,
+				Data:           json.RawMessage(`{"a":1}`),
+				Params:         map[string][]string{"path": {"/echo?x=1"}, "y": {"2"}},
+				RequestHeaders: map[string]string{"X-HTTP-Method": "put"},
+			},
+			status:  200,
+			typ:     e5e.ResultDataTypeObject,
+			data:    `{"body":"{\"a\":1}","content_type":"application/json","method":"PUT","path":"/echo","query":"x=1\u0026y=2"}`,
+			headers: map[string]string{"Content-Type": "application/json", "X-Values": "a, b"},
+		},
+		{
+			name: "text",
+			event: e5e.Event[json.RawMessage]{
+				Type:           e5e.EventDataTypeText,
+				Data:           json.RawMessage(`"hello"`),
+				RequestHeaders: map[string]string{"X-HTTP-Path": "/echo"},
+			},
+			status: 200,
+			typ:    e5e.ResultDataTypeObject,
+			data:   `{"body":"hello","content_type":"text/plain; charset=utf-8","method":"POST","path":"/echo","query":""}`,
+		},
+		{
+			name:   "text result",
+			event:  e5e.Event[json.RawMessage]{Params: map[string][]string{"path": {"text"}}},
+			status: 201,
+			typ:    e5e.ResultDataTypeText,
+			data:   `"hello"`,
+		},
+		{
+			name: "multipart form",
+			event: e5e.Event[json.RawMessage]{
+				Type:   e5e.EventDataTypeMixed,
+				Data:   json.RawMessage(`{"title":["greeting"],"file":[` + string(rawFile) + `]}`),
+				Params: map[string][]string{"path": {"/upload"}},
+			},
+			status: 200,
+			typ:    e5e.ResultDataTypeBinary,
+		},
+		{
+			name:   "not found",
+			event:  e5e.Event[json.RawMessage]{Params: map[string][]string{"path": {"/missing"}}},
+			status: 404,
+			typ:    e5e.ResultDataTypeText,
+			data:   `"404 page not found\n"`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			res, err := e5e.FromHTTPHandler(router).Handle(context.Background(), e5e.Request[json.RawMessage, json.RawMessage]{Event: tt.event})
+			if err != nil {
+				t.Fatalf("handling failed: %v", err)
+			}
+			Equal(t, tt.status, res.Status, "status does not match")
+			Equal(t, tt.typ, res.Type, "type does not match")
+			if tt.typ == e5e.ResultDataTypeBinary {
+				Equal(t, "greeting=hello.txt:hello world", string(res.Data.(e5e.File).Bytes()), "file content does not match")
+				return
+			}
+			data, _ := json.Marshal(res.Data)
+			Equal(t, tt.data, string(data), "data does not match")
+			for k, v := range tt.headers {
+				Equal(t, v, res.ResponseHeaders[k], "header "+k+" does not match")
+			}
+		})
+	}
+}
@@ -1,3 +1,3 @@
 module go.anx.io/e5e/v2
 
-go 1.18
+go 1.21
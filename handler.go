@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"time"
 )
 
 // A Handler responds to a request.
@@ -35,29 +37,71 @@ type HandlerFactory interface {
 	Execute(ctx context.Context, payload []byte) (*Result, error)
 }
 
+// HandlerFactoryFunc is an adapter to allow the use of ordinary functions as a [HandlerFactory].
+type HandlerFactoryFunc func(ctx context.Context, payload []byte) (*Result, error)
+
+// Execute calls f(ctx, payload).
+func (f HandlerFactoryFunc) Execute(ctx context.Context, payload []byte) (*Result, error) {
+	return f(ctx, payload)
+}
+
+// NewHandlerFactory returns a [HandlerFactory] that decodes the payload into a [Request]
+// and passes it to the given handler, in the same way as the runtime does for registered handlers.
+func NewHandlerFactory[T, TContext Data](h Handler[T, TContext]) HandlerFactory {
+	return &typedHandlerFactory[T, TContext]{h: h}
+}
+
+// Middleware wraps a [HandlerFactory] in order to add behaviour before or after its execution,
+// for example to modify the incoming payload or the returned [Result].
+//
+// Middleware is registered for all entrypoints using [Use].
+type Middleware func(next HandlerFactory) HandlerFactory
+
 type typedHandlerFactory[T, TContext Data] struct {
 	h Handler[T, TContext]
 }
 
 func (t *typedHandlerFactory[T, TContext]) Execute(ctx context.Context, payload []byte) (*Result, error) {
+	start := time.Now()
 	var request Request[T, TContext]
 	if err := json.Unmarshal(payload, &request); err != nil {
 		return nil, fmt.Errorf("unmarshaling JSON failed: %w", err)
 	}
+	observeDecodeDuration(ctx, time.Since(start))
 
 	return t.h.Handle(ctx, request)
 }
 
-type handlerFunc[T, TContext Data] func(context.Context, Request[T, TContext]) (*Result, error)
+// requestTypes returns the names of the Go types the event data and the context data are decoded into.
+func (t *typedHandlerFactory[T, TContext]) requestTypes() (string, string) {
+	return reflect.TypeOf((*T)(nil)).Elem().String(), reflect.TypeOf((*TContext)(nil)).Elem().String()
+}
+
+// HandlerFunc is an adapter to allow the use of ordinary functions as a [Handler].
+type HandlerFunc[T, TContext Data] func(context.Context, Request[T, TContext]) (*Result, error)
 
-func (h handlerFunc[T, TContext]) Handle(ctx context.Context, evt Request[T, TContext]) (*Result, error) {
+// Handle calls h(ctx, evt).
+func (h HandlerFunc[T, TContext]) Handle(ctx context.Context, evt Request[T, TContext]) (*Result, error) {
 	return h(ctx, evt)
 }
 
 func createHandlerFunc[T, TContext Data](f func(context.Context, Request[T, TContext]) (*Result, error)) Handler[T, TContext] {
-	return handlerFunc[T, TContext](f)
+	return HandlerFunc[T, TContext](f)
+}
+
+// rawRequest is a [Request] whose data is kept in its JSON representation.
+// It's used by middleware that needs to inspect or modify the request envelope without knowing the data types.
+type rawRequest = Request[json.RawMessage, json.RawMessage]
+
+// decodeRawRequest decodes the given payload into a [rawRequest].
+func decodeRawRequest(payload []byte) (rawRequest, error) {
+	var request rawRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return request, fmt.Errorf("unmarshaling JSON failed: %w", err)
+	}
+	return request, nil
 }
 
 // Handlers returns all registered handlers.
-// It should not be modified directly, instead add new handlers only via [AddHandlerFunc].
+// It should not be modified directly, instead add new handlers only via [AddHandlerFunc] or [AddHandler].
 func Handlers() map[string]HandlerFactory { return globalMux.handlers }
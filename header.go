@@ -0,0 +1,54 @@
+package e5e
+
+import "strings"
+
+// Header returns the value of the given request header.
+// The lookup is case-insensitive, as header names are in HTTP.
+// If the header was not sent, an empty string is returned.
+func (e Event[T]) Header(name string) string {
+	return headerValue(e.RequestHeaders, name)
+}
+
+// headerValue returns the value of the header with the given name, ignoring the case of the name.
+func headerValue(headers map[string]string, name string) string {
+	if v, ok := headers[name]; ok {
+		return v
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// setHeader sets the header with the given name and replaces any existing header whose name only differs in case.
+// The map is allocated if it's nil and is returned in any case.
+func setHeader(headers map[string]string, name, value string) map[string]string {
+	headers = deleteHeader(headers, name)
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	headers[name] = value
+	return headers
+}
+
+// deleteHeader removes all headers with the given name, ignoring the case of the name.
+func deleteHeader(headers map[string]string, name string) map[string]string {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			delete(headers, k)
+		}
+	}
+	return headers
+}
+
+// cloneHeaders returns a shallow copy of the given headers, so they can be modified
+// without changing the map the handler returned.
+func cloneHeaders(headers map[string]string) map[string]string {
+	clone := make(map[string]string, len(headers))
+	for k, v := range headers {
+		clone[k] = v
+	}
+	return clone
+}
@@ -0,0 +1,201 @@
+package e5e
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxHTTPMemory is the maximum number of bytes of a multipart request body that are kept in memory.
+const maxHTTPMemory = 32 << 20
+
+// defaultMaxHTTPRequestBytes is the maximum size of HTTP request bodies, if [HTTPHandlerOptions.MaxRequestBytes] is not set.
+const defaultMaxHTTPRequestBytes = 32 << 20
+
+// contextDateLayout is the layout E5E uses for [Context.Date].
+const contextDateLayout = "2006-01-02T15:04:05.000000"
+
+// requestFromHTTP converts the given HTTP request into a request envelope, as E5E would do:
+//
+//   - The query parameters become the [Event.Params].
+//   - The headers become the [Event.RequestHeaders], multiple values are joined with a comma.
+//   - JSON bodies become [EventDataTypeObject], text bodies become [EventDataTypeText],
+//     multipart forms become [EventDataTypeMixed] and all other bodies become [EventDataTypeBinary].
+//
+// The returned error is caused by an invalid request body.
+func requestFromHTTP(r *http.Request) (rawRequest, error) {
+	request := rawRequest{
+		Context: Context[json.RawMessage]{
+			Date: time.Now().UTC().Format(contextDateLayout),
+			Type: "http",
+		},
+		Event: Event[json.RawMessage]{
+			RequestHeaders: make(map[string]string, len(r.Header)),
+		},
+	}
+	if query := r.URL.Query(); len(query) > 0 {
+		request.Event.Params = query
+	}
+	for k, v := range r.Header {
+		request.Event.RequestHeaders[k] = strings.Join(v, ", ")
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if mediaType == "multipart/form-data" {
+		data, err := mixedDataFromHTTP(r)
+		if err != nil {
+			return request, err
+		}
+		request.Event.Type, request.Event.Data = EventDataTypeMixed, data
+		return request, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return request, fmt.Errorf("reading request body: %w", err)
+	}
+	if len(body) == 0 {
+		return request, nil
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		if !json.Valid(body) {
+			return request, errors.New("request body is not valid JSON")
+		}
+		request.Event.Type, request.Event.Data = EventDataTypeObject, body
+	case strings.HasPrefix(mediaType, "text/"):
+		request.Event.Type = EventDataTypeText
+		request.Event.Data, err = json.Marshal(string(body))
+	default:
+		file := File{ContentType: mediaType, Charset: params["charset"]}
+		_, _ = file.Write(body)
+		request.Event.Type = EventDataTypeBinary
+		request.Event.Data, err = json.Marshal(file)
+	}
+	return request, err
+}
+
+// mixedDataFromHTTP converts a multipart form into the data of a [EventDataTypeMixed] event.
+func mixedDataFromHTTP(r *http.Request) (json.RawMessage, error) {
+	if err := r.ParseMultipartForm(maxHTTPMemory); err != nil {
+		return nil, fmt.Errorf("parsing multipart form: %w", err)
+	}
+	defer func() { _ = r.MultipartForm.RemoveAll() }()
+
+	fields := make(map[string][]any)
+	for name, values := range r.MultipartForm.Value {
+		for _, v := range values {
+			fields[name] = append(fields[name], v)
+		}
+	}
+	for name, headers := range r.MultipartForm.File {
+		for _, header := range headers {
+			f, err := header.Open()
+			if err != nil {
+				return nil, fmt.Errorf("opening uploaded file %q: %w", header.Filename, err)
+			}
+			content, err := io.ReadAll(f)
+			_ = f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("reading uploaded file %q: %w", header.Filename, err)
+			}
+
+			file := File{Name: header.Filename}
+			file.ContentType, _, _ = strings.Cut(header.Header.Get("Content-Type"), ";")
+			_, _ = file.Write(content)
+			fields[name] = append(fields[name], file)
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// writeHTTPResult writes the given result as HTTP response, as E5E would do.
+// If the result is nil, the response has the status 204 and no body.
+func writeHTTPResult(w http.ResponseWriter, res *Result) error {
+	if res == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	body, contentType, err := resultBody(res)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range res.ResponseHeaders {
+		w.Header().Set(k, v)
+	}
+	if contentType != "" && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	status := res.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+	return err
+}
+
+// resultBody returns the encoded data of the result and its content type.
+func resultBody(res *Result) ([]byte, string, error) {
+	if res.Data == nil {
+		return nil, "", nil
+	}
+
+	switch res.Type {
+	case ResultDataTypeText:
+		if text, ok := res.Data.(string); ok {
+			return []byte(text), "text/plain; charset=utf-8", nil
+		}
+		return []byte(fmt.Sprint(res.Data)), "text/plain; charset=utf-8", nil
+	case ResultDataTypeBinary:
+		file, err := resultFile(res)
+		if err != nil {
+			return nil, "", err
+		}
+		contentType := file.ContentType
+		if strings.HasPrefix(contentType, "text/") && file.Charset != "" && !strings.Contains(contentType, "charset=") {
+			contentType += "; charset=" + file.Charset
+		}
+		return file.content, contentType, nil
+	default:
+		body, err := json.Marshal(res.Data)
+		if err != nil {
+			return nil, "", fmt.Errorf("marshalling result data: %w", err)
+		}
+		return body, "application/json", nil
+	}
+}
+
+// resultFile returns the file of a binary result. Anything that marshals to a [File] is supported.
+func resultFile(res *Result) (File, error) {
+	switch f := res.Data.(type) {
+	case File:
+		return f, nil
+	case *File:
+		// A nil file has no content.
+		if f == nil {
+			return File{}, nil
+		}
+		return *f, nil
+	}
+
+	var file File
+	encoded, err := json.Marshal(res.Data)
+	if err != nil {
+		return file, fmt.Errorf("marshalling binary result data: %w", err)
+	}
+	if err := json.Unmarshal(encoded, &file); err != nil {
+		return file, fmt.Errorf("binary result data is not a file: %w", err)
+	}
+	return file, nil
+}
@@ -0,0 +1,139 @@
+package e5e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// invocationEnvelope contains the parts of the request envelope that are needed to set up an invocation.
+// The event data is skipped while decoding.
+type invocationEnvelope struct {
+	Context struct {
+		Type  string `json:"type"`
+		Async bool   `json:"async"`
+	} `json:"context"`
+	Event struct {
+		Type           EventDataType     `json:"type"`
+		RequestHeaders map[string]string `json:"request_headers"`
+	} `json:"event"`
+}
+
+// withInvocation wraps the handler of an entrypoint, so each execution gets its own invocation context:
+//
+//   - The request ID returned by [RequestID].
+//   - Whether it's the cold start, see [IsColdStart].
+//   - The memory watchdog, see [ErrMemoryLimitExceeded].
+//   - The logger returned by [Logger].
+//   - The span of the invocation, see [Span].
+//   - The metrics of the invocation, see [WriteMetrics].
+func withInvocation(entrypoint string, next HandlerFactory) HandlerFactory {
+	return HandlerFactoryFunc(func(ctx context.Context, payload []byte) (res *Result, err error) {
+		start := time.Now()
+		defer func() {
+			v := recover()
+			invocationMetrics.update(entrypoint, func(e *entrypointMetrics) {
+				e.invocations++
+				if v != nil {
+					e.panics++
+				} else if err != nil {
+					e.errors++
+				}
+				e.duration.observe(time.Since(start).Seconds())
+				e.requestBytes.observe(float64(len(payload)))
+			})
+			if v != nil {
+				panic(v)
+			}
+		}()
+
+		closeLogFrame := logOutput.OpenFrame()
+		defer closeLogFrame()
+
+		coldStart := startup.markEvent()
+
+		var envelope invocationEnvelope
+		_ = json.Unmarshal(payload, &envelope)
+
+		parent, _ := parseTraceParent(headerValue(envelope.Event.RequestHeaders, "traceparent"))
+		if parent.IsValid() {
+			parent.TraceState = headerValue(envelope.Event.RequestHeaders, "tracestate")
+		}
+		ctx, span := startSpan(ctx, entrypoint, SpanKindServer, parent)
+		span.SetAttribute("e5e.entrypoint", entrypoint)
+		span.SetAttribute("e5e.trigger", envelope.Context.Type)
+		span.SetAttribute("e5e.async", envelope.Context.Async)
+		span.SetAttribute("e5e.event.type", string(envelope.Event.Type))
+		span.SetAttribute("e5e.request.size", len(payload))
+		span.SetAttribute("e5e.cold_start", coldStart)
+
+		requestID := requestIDFromHeaders(envelope.Event.RequestHeaders)
+		span.SetAttribute("e5e.request_id", requestID)
+
+		ctx = context.WithValue(ctx, decodeStatsContextKey{}, entrypoint)
+		ctx = context.WithValue(ctx, requestIDContextKey{}, requestID)
+		ctx = context.WithValue(ctx, coldStartContextKey{}, coldStart)
+		ctx = context.WithValue(ctx, loggerContextKey{}, logger.With(
+			slog.String("entrypoint", entrypoint),
+			slog.String("invocation_id", newInvocationID()),
+			slog.String("request_id", requestID),
+			slog.String("trigger", envelope.Context.Type),
+			slog.Bool("async", envelope.Context.Async),
+			slog.Bool("cold_start", coldStart),
+			slog.String("trace_id", span.SpanContext().TraceID.String()),
+		))
+		if coldStart {
+			startup.log(Logger(ctx))
+		}
+
+		defer func() {
+			if err != nil {
+				Logger(ctx).Error("handler returned an error", "error", err.Error())
+			}
+			endInvocationSpan(span, res, err)
+			closeLogFrame()
+			if err != nil {
+				err = &requestError{requestID: requestID, err: err}
+			}
+		}()
+		watchedCtx, stopWatching := memory.watch(ctx)
+		defer stopWatching()
+		res, err = next.Execute(watchedCtx, payload)
+		if stopWatching() {
+			Logger(ctx).Warn("memory limit exceeded, the invocation was cancelled", "soft_limit", memory.softLimit)
+			invocationMetrics.update(entrypoint, func(e *entrypointMetrics) { e.memoryExceeded++ })
+			res, err = memoryLimitExceededResult(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return echoRequestID(res, requestID), nil
+	})
+}
+
+// endInvocationSpan records the outcome of the invocation and ends its span.
+func endInvocationSpan(span *Span, res *Result, err error) {
+	defer span.End()
+	if err != nil {
+		span.SetError(err)
+		return
+	}
+	if res == nil || !span.SpanContext().Sampled {
+		return
+	}
+
+	status := res.Status
+	if status == 0 {
+		status = 200
+	}
+	span.SetAttribute("e5e.result.status", status)
+	span.SetAttribute("e5e.result.type", string(res.Type))
+	if encoded, err := MarshalResult(res); err == nil {
+		span.SetAttribute("e5e.response.size", len(encoded))
+	}
+	if status >= 500 {
+		span.SetError(fmt.Errorf("status %d", status))
+	}
+}
@@ -0,0 +1,273 @@
+package e5e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// invokeUsage describes the arguments of the "invoke" command.
+const invokeUsage = "invoke <entrypoint> [--data <json> | --text <text> | --file [name=]@<path> ...] [--field name=value ...] " +
+	"[--param key=value ...] [--header key=value ...] [--context-data <json>] [--trigger <type>] [--async] [--output <path>]"
+
+// repeatedFlag collects all values of a flag that may be given multiple times.
+type repeatedFlag []string
+
+func (f *repeatedFlag) String() string     { return strings.Join(*f, ", ") }
+func (f *repeatedFlag) Set(v string) error { *f = append(*f, v); return nil }
+
+// invokeCommand builds a request envelope from the command line arguments, executes the handler of the entrypoint
+// through the runtime and prints the result to [os.Stdout]. It returns the exit code.
+//
+// Files given without a field name, e.g. "--file @image.png", are sent as [EventDataTypeBinary] event.
+// Files with a field name, e.g. "--file upload=@image.png", and "--field" values are sent as [EventDataTypeMixed] event.
+// Binary results are written to the path given by "--output", or to the name of the result file in the working directory,
+// unless a file with that name already exists.
+func invokeCommand(ctx context.Context, args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		exitWithUsage(invokeUsage)
+	}
+	entrypoint := args[0]
+
+	var (
+		data, text, contextData, trigger, output string
+		async                                    bool
+		files, fields, params, headers           repeatedFlag
+	)
+	flags := flag.NewFlagSet("invoke", flag.ContinueOnError)
+	flags.StringVar(&data, "data", "", "JSON encoded event data")
+	flags.StringVar(&text, "text", "", "text event data")
+	flags.Var(&files, "file", "file to upload, either `[name=]@path`")
+	flags.Var(&fields, "field", "form field as `name=value`")
+	flags.Var(&params, "param", "GET parameter as `key=value`")
+	flags.Var(&headers, "header", "request header as `key=value`")
+	flags.StringVar(&contextData, "context-data", "", "JSON encoded context data")
+	flags.StringVar(&trigger, "trigger", "invoke", "the type of the trigger")
+	flags.BoolVar(&async, "async", false, "mark the event as triggered asynchronously")
+	flags.StringVar(&output, "output", "", "path to write binary results to")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if _, ok := globalMux.handlers[entrypoint]; !ok {
+		_, _ = fmt.Fprintf(os.Stderr, "go-e5e: %v\n", InvalidEntrypointError{Entrypoint: entrypoint})
+		return 1
+	}
+
+	request := rawRequest{
+		Context: Context[json.RawMessage]{
+			Async: async,
+			Date:  time.Now().UTC().Format(contextDateLayout),
+			Type:  trigger,
+		},
+	}
+	var err error
+	if contextData != "" {
+		if request.Context.Data, err = validJSON("--context-data", contextData); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "go-e5e: %v\n", err)
+			return 2
+		}
+	}
+	if request.Event, err = invokeEvent(data, text, files, fields); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "go-e5e: %v\n", err)
+		return 2
+	}
+	for _, p := range params {
+		k, v, _ := strings.Cut(p, "=")
+		if request.Event.Params == nil {
+			request.Event.Params = make(map[string][]string)
+		}
+		request.Event.Params[k] = append(request.Event.Params[k], v)
+	}
+	for _, h := range headers {
+		k, v, _ := strings.Cut(h, "=")
+		request.Event.RequestHeaders = setHeader(request.Event.RequestHeaders, k, v)
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "go-e5e: encoding request: %v\n", err)
+		return 1
+	}
+
+	start := time.Now()
+	res, err := globalMux.factory(entrypoint).Execute(ctx, payload)
+	duration := time.Since(start)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "go-e5e: executing handler %q: %v\n", entrypoint, err)
+		return 1
+	}
+	if err := printInvokeResult(res, output, duration); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "go-e5e: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// invokeEvent builds the event from the data flags of the "invoke" command.
+func invokeEvent(data, text string, files, fields []string) (Event[json.RawMessage], error) {
+	var event Event[json.RawMessage]
+	var err error
+	switch {
+	case data != "":
+		event.Type = EventDataTypeObject
+		event.Data, err = validJSON("--data", data)
+	case text != "":
+		event.Type = EventDataTypeText
+		event.Data, err = json.Marshal(text)
+	case len(files) == 1 && strings.HasPrefix(files[0], "@") && len(fields) == 0:
+		var file File
+		if file, err = readInvokeFile(files[0][1:]); err == nil {
+			event.Type = EventDataTypeBinary
+			event.Data, err = json.Marshal(file)
+		}
+	case len(files) > 0 || len(fields) > 0:
+		mixed := make(map[string][]any)
+		for _, f := range fields {
+			k, v, _ := strings.Cut(f, "=")
+			mixed[k] = append(mixed[k], v)
+		}
+		for _, f := range files {
+			name, path, found := strings.Cut(f, "=@")
+			if !found {
+				return event, fmt.Errorf("--file %q must be given as name=@path when sending multiple files or fields", f)
+			}
+			file, err := readInvokeFile(path)
+			if err != nil {
+				return event, err
+			}
+			mixed[name] = append(mixed[name], file)
+		}
+		event.Type = EventDataTypeMixed
+		event.Data, err = json.Marshal(mixed)
+	}
+	return event, err
+}
+
+func validJSON(flagName, value string) (json.RawMessage, error) {
+	if !json.Valid([]byte(value)) {
+		return nil, fmt.Errorf("%s does not contain valid JSON", flagName)
+	}
+	return json.RawMessage(value), nil
+}
+
+func readInvokeFile(path string) (File, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, fmt.Errorf("reading file to upload: %w", err)
+	}
+	file := File{Name: filepath.Base(path)}
+	if contentType := mime.TypeByExtension(filepath.Ext(path)); contentType != "" {
+		file.ContentType, _, _ = strings.Cut(contentType, ";")
+	}
+	_, _ = file.Write(content)
+	return file, nil
+}
+
+// printInvokeResult prints the result in a human-readable way, after it got encoded and decoded
+// again like E5E would do it. Binary results are written to a file instead.
+func printInvokeResult(res *Result, output string, duration time.Duration) error {
+	encoded, err := MarshalResult(res)
+	if err != nil {
+		return err
+	}
+	var decoded struct {
+		Result *struct {
+			Status          int               `json:"status"`
+			ResponseHeaders map[string]string `json:"response_headers"`
+			Data            json.RawMessage   `json:"data"`
+			Type            ResultDataType    `json:"type"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	defer func() { _, _ = os.Stdout.Write(out.Bytes()) }()
+	_, _ = fmt.Fprintf(&out, "Duration: %s\n", duration.Round(time.Microsecond))
+	if decoded.Result == nil {
+		out.WriteString("Result:   none\n")
+		return nil
+	}
+
+	r := decoded.Result
+	// E5E responds with 200, if the handler did not set the status.
+	status := r.Status
+	if status == 0 {
+		status = 200
+	}
+	_, _ = fmt.Fprintf(&out, "Status:   %d\n", status)
+	if r.Type != "" {
+		_, _ = fmt.Fprintf(&out, "Type:     %s\n", r.Type)
+	}
+	if len(r.ResponseHeaders) > 0 {
+		out.WriteString("Headers:\n")
+		names := make([]string, 0, len(r.ResponseHeaders))
+		for name := range r.ResponseHeaders {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			_, _ = fmt.Fprintf(&out, "  %s: %s\n", name, r.ResponseHeaders[name])
+		}
+	}
+
+	// Files are encoded with their own type, even if the type of the result is not set.
+	var probe struct {
+		Type   string  `json:"type"`
+		Binary *string `json:"binary"`
+	}
+	if r.Type == ResultDataTypeBinary || json.Unmarshal(r.Data, &probe) == nil && probe.Type == "binary" && probe.Binary != nil {
+		var file File
+		if err := json.Unmarshal(r.Data, &file); err != nil {
+			return fmt.Errorf("decoding binary result: %w", err)
+		}
+		// Without an explicit output path, existing files are never overwritten.
+		path, flags := output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC
+		if path == "" {
+			path, flags = filepath.Base(file.Name), os.O_WRONLY|os.O_CREATE|os.O_EXCL
+		}
+		if path == "" || path == "." || path == string(filepath.Separator) {
+			path = "result.bin"
+		}
+		if err := writeInvokeResult(path, flags, file.content); err != nil {
+			if errors.Is(err, os.ErrExist) {
+				return fmt.Errorf("writing binary result: %s already exists, choose another path with --output", path)
+			}
+			return fmt.Errorf("writing binary result: %w", err)
+		}
+		_, _ = fmt.Fprintf(&out, "Data:     %d bytes of %s written to %s\n", len(file.content), file.ContentType, path)
+		return nil
+	}
+
+	out.WriteString("Data:\n")
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, r.Data, "", "  "); err != nil {
+		return errors.New("result data is not valid JSON")
+	}
+	out.Write(indented.Bytes())
+	out.WriteByte('\n')
+	return nil
+}
+
+func writeInvokeResult(path string, flags int, content []byte) error {
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,118 @@
+package e5e_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestInvokeCommand(t *testing.T) {
+	e5e.AddHandlerFunc(t.Name(), func(ctx context.Context, r e5e.Request[IntegrationTestPayload, IntegrationTestContext]) (*e5e.Result, error) {
+		return &e5e.Result{
+			Status:          201,
+			ResponseHeaders: map[string]string{"X-Sum-Of": r.Event.Params["name"][0]},
+			Data:            map[string]int{"sum": r.Data().A + r.Data().B},
+		}, nil
+	})
+	e5e.AddHandlerFunc(t.Name()+"_binary", func(ctx context.Context, r e5e.Request[e5e.File, any]) (*e5e.Result, error) {
+		file := r.Data()
+		file.Name = "echo.txt"
+		return &e5e.Result{Data: file}, nil
+	})
+
+	e5e.AddHandlerFunc(t.Name()+"_default", func(ctx context.Context, r e5e.Request[IntegrationTestPayload, any]) (*e5e.Result, error) {
+		return &e5e.Result{Data: r.Data().A + r.Data().B}, nil
+	})
+
+	args := os.Args
+	t.Cleanup(func() { os.Args = args })
+
+	t.Run("object", func(t *testing.T) {
+		stdio := redirectStdio(t, "")
+		os.Args = []string{"test-binary", "invoke", "TestInvokeCommand", "--data", `{"a":2,"b":3}`, "--param", "name=numbers", "--header", "X-Request-Id=test-request-id"}
+		e5e.Start(context.Background())
+		stdout, _ := stdio.ReadAndRestore()
+
+		lines := strings.Split(stdout, "\n")
+		Equal(t, true, strings.HasPrefix(lines[0], "Duration: "), "duration is missing")
+		Equal(t, "Status:   201\nHeaders:\n  X-Request-Id: test-request-id\n  X-Sum-Of: numbers\nData:\n{\n  \"sum\": 5\n}\n",
+			strings.Join(lines[1:], "\n"), "output does not match")
+	})
+	t.Run("five arguments and default status", func(t *testing.T) {
+		stdio := redirectStdio(t, "")
+		os.Args = []string{"test-binary", "invoke", "TestInvokeCommand_default", "--data", `{"a":2,"b":3}`}
+		e5e.Start(context.Background())
+		stdout, _ := stdio.ReadAndRestore()
+
+		lines := strings.Split(stdout, "\n")
+		Equal(t, "Status:   200", lines[1], "status does not match")
+		Equal(t, true, strings.HasSuffix(stdout, "Data:\n5\n"), "output does not match: "+stdout)
+	})
+	t.Run("binary", func(t *testing.T) {
+		dir := t.TempDir()
+		input := filepath.Join(dir, "input.txt")
+		if err := os.WriteFile(input, []byte("hello world"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		output := filepath.Join(dir, "output.txt")
+
+		stdio := redirectStdio(t, "")
+		os.Args = []string{"test-binary", "invoke", "TestInvokeCommand_binary", "--file", "@" + input, "--output", output}
+		e5e.Start(context.Background())
+		stdout, _ := stdio.ReadAndRestore()
+
+		Equal(t, true, strings.HasSuffix(stdout, "Data:     11 bytes of text/plain written to "+output+"\n"), "output does not match: "+stdout)
+		written, err := os.ReadFile(output)
+		if err != nil {
+			t.Fatalf("reading written result failed: %v", err)
+		}
+		Equal(t, "hello world", string(written), "written result does not match")
+	})
+}
+
+// TestInvokeCommandKeepsExistingFiles runs in a separate process, since the command exits on failures.
+func TestInvokeCommandKeepsExistingFiles(t *testing.T) {
+	if os.Getenv("E5E_TEST_INVOKE_EXISTING") == "1" {
+		e5e.AddHandlerFunc(t.Name(), func(ctx context.Context, r e5e.Request[any, any]) (*e5e.Result, error) {
+			file := e5e.File{Name: "result.txt"}
+			_, _ = file.Write([]byte("new"))
+			return &e5e.Result{Data: file}, nil
+		})
+		os.Args = []string{"test-binary", "invoke", t.Name(), "--text", "ignored", "--async"}
+		e5e.Start(context.Background())
+		os.Exit(0)
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		t.Fatalf("locating the test binary failed: %v", err)
+	}
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "result.txt")
+	if err := os.WriteFile(existing, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(binary, "-test.run=^TestInvokeCommandKeepsExistingFiles$")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "E5E_TEST_INVOKE_EXISTING=1")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+
+	var exitErr *exec.ExitError
+	Equal(t, true, errors.As(err, &exitErr) && exitErr.ExitCode() == 1, "command did not fail")
+	Equal(t, true, strings.Contains(stderr.String(), "result.txt already exists"), "error does not match: "+stderr.String())
+	written, err := os.ReadFile(existing)
+	if err != nil {
+		t.Fatalf("reading existing file failed: %v", err)
+	}
+	Equal(t, "old", string(written), "existing file was overwritten")
+}
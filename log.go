@@ -0,0 +1,135 @@
+package e5e
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// envLogLevel is the environment variable that sets the minimum level of the logs written by [Logger].
+const envLogLevel = "E5E_LOG_LEVEL"
+
+// maxBufferedLogs is the number of bytes of logs that are buffered before they're written to [os.Stderr].
+const maxBufferedLogs = 64 * 1024
+
+var (
+	logLevel  = new(slog.LevelVar)
+	logOutput = &logBuffer{}
+	logger    = slog.New(slog.NewJSONHandler(logOutput, &slog.HandlerOptions{Level: logLevel}))
+)
+
+// Logger returns the logger for the invocation the context belongs to.
+//
+// Each log line is written as JSON object to [os.Stderr] and contains the entrypoint, the invocation ID,
+// the request ID (see [RequestID]), the trigger type, whether the event was triggered asynchronously
+// and whether it's the cold start (see [IsColdStart]), so all lines of an invocation can be correlated.
+// Outside of an invocation, the logger has none of these attributes.
+//
+// The minimum level is set by the environment variable E5E_LOG_LEVEL, e.g. "debug" or "warn".
+// It defaults to "info".
+//
+// During an invocation, the logs are buffered and written at the latest after the invocation finished,
+// so they never get mixed up with the output of the runtime. Otherwise, they're written immediately.
+func Logger(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return logger
+}
+
+// loggerContextKey is the context key of the logger of an invocation.
+type loggerContextKey struct{}
+
+// configureLogging sets the log level from the environment.
+func configureLogging() error {
+	level := os.Getenv(envLogLevel)
+	if level == "" {
+		logLevel.Set(slog.LevelInfo)
+		return nil
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("go-e5e: invalid %s: %w", envLogLevel, err)
+	}
+	logLevel.Set(l)
+	return nil
+}
+
+// newInvocationID returns a random ID for an invocation.
+func newInvocationID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// flushLogs writes all buffered logs to [os.Stderr].
+func flushLogs() { logOutput.Flush() }
+
+// logBuffer collects log lines while an invocation frame is open and writes them to [os.Stderr] once it's flushed,
+// the last frame is closed or the buffer gets too large. Outside of frames, log lines are written directly.
+type logBuffer struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	frames int
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.frames == 0 {
+		b.flushLocked(os.Stderr)
+		return os.Stderr.Write(p)
+	}
+	n, _ := b.buf.Write(p)
+	if b.buf.Len() >= maxBufferedLogs {
+		b.flushLocked(os.Stderr)
+	}
+	return n, nil
+}
+
+// OpenFrame starts buffering the logs until the returned function is called,
+// which flushes the buffered logs. Frames may be open concurrently.
+func (b *logBuffer) OpenFrame() (closeFrame func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.frames++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			b.frames--
+			b.flushLocked(os.Stderr)
+		})
+	}
+}
+
+// Flush writes the buffered logs to [os.Stderr].
+func (b *logBuffer) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushLocked(os.Stderr)
+}
+
+// WriteAfterFlush writes the buffered logs, followed by s, to w.
+// No log line can be written in between.
+func (b *logBuffer) WriteAfterFlush(w io.Writer, s string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushLocked(w)
+	_, _ = io.WriteString(w, s)
+}
+
+func (b *logBuffer) flushLocked(w io.Writer) {
+	if b.buf.Len() == 0 {
+		return
+	}
+	_, _ = w.Write(b.buf.Bytes())
+	b.buf.Reset()
+}
@@ -0,0 +1,54 @@
+package e5e_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestLogger(t *testing.T) {
+	t.Setenv("E5E_LOG_LEVEL", "debug")
+
+	e5e.AddHandlerFunc(t.Name(), func(ctx context.Context, r e5e.Request[IntegrationTestPayload, IntegrationTestContext]) (*e5e.Result, error) {
+		e5e.Logger(ctx).Debug("summing", "a", r.Data().A)
+		return &e5e.Result{Data: r.Data().A + r.Data().B}, nil
+	})
+
+	stdio := redirectStdio(t, string(defaultPayload)+"\n"+string(defaultPayload)+"\n")
+	os.Args = buildOptions(t.Name())
+	os.Args[3] = "1"
+	e5e.Start(context.Background())
+	_, stderr := stdio.ReadAndRestore()
+
+	frames := strings.Split(stderr, daemonTerminationSequence)
+	Equal(t, 3, len(frames), "number of stderr frames does not match")
+	Equal(t, "", frames[2], "logs must be written before the daemon sequence")
+
+	var invocationIDs []string
+	for _, frame := range frames[:2] {
+		var line map[string]any
+		if err := json.Unmarshal([]byte(frame), &line); err != nil {
+			t.Fatalf("log line %q is not valid JSON: %v", frame, err)
+		}
+		Equal[any](t, "DEBUG", line["level"], "level does not match")
+		Equal[any](t, "summing", line["msg"], "message does not match")
+		Equal[any](t, float64(2), line["a"], "attribute does not match")
+		Equal[any](t, t.Name(), line["entrypoint"], "entrypoint does not match")
+		Equal[any](t, "go-library-test", line["trigger"], "trigger does not match")
+		Equal[any](t, false, line["async"], "async does not match")
+		invocationIDs = append(invocationIDs, line["invocation_id"].(string))
+	}
+	Equal(t, true, invocationIDs[0] != invocationIDs[1], "invocation IDs must differ")
+}
+
+func TestLoggerOutsideOfInvocations(t *testing.T) {
+	stdio := redirectStdio(t, "")
+	e5e.Logger(context.Background()).Info("outside")
+	_, stderr := stdio.ReadAndRestore()
+
+	Equal(t, true, strings.Contains(stderr, `"msg":"outside"`), "log line was not written immediately: "+stderr)
+}
@@ -0,0 +1,191 @@
+package e5e
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	rtmetrics "runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Environment variables that control the memory watchdog, see the package documentation.
+const (
+	envMemoryLimit     = "E5E_MEMORY_LIMIT"
+	envMemorySoftLimit = "E5E_MEMORY_SOFT_LIMIT"
+	envMemoryRelease   = "E5E_MEMORY_RELEASE"
+)
+
+const (
+	// defaultMemorySoftLimit is the share of the memory limit at which invocations are cancelled.
+	defaultMemorySoftLimit = 0.95
+
+	// goMemoryLimitRatio is the share of the memory limit that is set as limit of the Go runtime,
+	// leaving room for memory that is not managed by it.
+	goMemoryLimitRatio = 0.9
+
+	// memoryCheckInterval is the interval the memory usage is checked in during an invocation.
+	memoryCheckInterval = 50 * time.Millisecond
+)
+
+// cgroupMemoryLimitFiles contain the memory limit of the container, for cgroup v2 and v1.
+var cgroupMemoryLimitFiles = []string{"/sys/fs/cgroup/memory.max", "/sys/fs/cgroup/memory/memory.limit_in_bytes"}
+
+// memory contains the configuration of the memory watchdog.
+var memory = &memoryWatchdog{}
+
+// memoryWatchdog cancels invocations before the process is killed for exceeding its memory limit.
+type memoryWatchdog struct {
+	// The memory limit of the process in bytes, or 0 if it's unknown.
+	limit int64
+
+	// The memory usage in bytes at which invocations are cancelled, or 0 if the watchdog is disabled.
+	softLimit int64
+
+	// How memory is released between invocations in keepalive mode: "gc", "free" or "" for not at all.
+	release string
+}
+
+// configureMemory reads the memory limit from the environment or the cgroup of the process,
+// sets the limit of the Go runtime and configures the watchdog.
+func configureMemory() error {
+	*memory = memoryWatchdog{limit: cgroupMemoryLimit()}
+	if v := os.Getenv(envMemoryLimit); v != "" {
+		limit, err := parseByteSize(v)
+		if err != nil {
+			return fmt.Errorf("go-e5e: invalid %s %q", envMemoryLimit, v)
+		}
+		memory.limit = limit
+	}
+
+	ratio := defaultMemorySoftLimit
+	if v := os.Getenv(envMemorySoftLimit); v != "" {
+		var err error
+		if ratio, err = strconv.ParseFloat(v, 64); err != nil || ratio < 0 || ratio > 1 {
+			return fmt.Errorf("go-e5e: invalid %s %q", envMemorySoftLimit, v)
+		}
+	}
+	switch v := os.Getenv(envMemoryRelease); v {
+	case "", "none":
+	case "gc", "free":
+		memory.release = v
+	default:
+		return fmt.Errorf("go-e5e: invalid %s %q", envMemoryRelease, v)
+	}
+
+	if memory.limit == 0 {
+		return nil
+	}
+	memory.softLimit = int64(float64(memory.limit) * ratio)
+	if os.Getenv("GOMEMLIMIT") == "" {
+		debug.SetMemoryLimit(int64(float64(memory.limit) * goMemoryLimitRatio))
+	}
+	return nil
+}
+
+// cgroupMemoryLimit returns the memory limit of the cgroup of the process, or 0 if there's none.
+func cgroupMemoryLimit() int64 {
+	for _, path := range cgroupMemoryLimitFiles {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		// cgroup v1 reports a value close to the maximum int64 if there's no limit.
+		limit, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+		if err != nil || limit <= 0 || limit >= 1<<62 {
+			return 0
+		}
+		return limit
+	}
+	return 0
+}
+
+// parseByteSize parses a number of bytes with an optional unit, like "512MiB", in the format of GOMEMLIMIT.
+func parseByteSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		factor int64
+	}{{"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}, {"B", 1}}
+	factor := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(s, unit.suffix) {
+			s, factor = strings.TrimSuffix(s, unit.suffix), unit.factor
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * factor, nil
+}
+
+// usedMemory returns the memory the Go runtime currently occupies from the operating system.
+func usedMemory() int64 {
+	samples := []rtmetrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	rtmetrics.Read(samples)
+	return int64(samples[0].Value.Uint64() - samples[1].Value.Uint64())
+}
+
+// watch monitors the memory usage until the returned stop function is called.
+// If the soft limit is exceeded, the returned context is cancelled with [ErrMemoryLimitExceeded].
+// The stop function returns whether that happened. It may be called multiple times.
+func (w *memoryWatchdog) watch(ctx context.Context) (context.Context, func() bool) {
+	if w.softLimit == 0 {
+		return ctx, func() bool { return false }
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	var exceeded atomic.Bool
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(memoryCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if usedMemory() >= w.softLimit {
+					exceeded.Store(true)
+					cancel(ErrMemoryLimitExceeded)
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return ctx, func() bool {
+		once.Do(func() {
+			close(done)
+			<-stopped
+			cancel(nil)
+		})
+		return exceeded.Load()
+	}
+}
+
+// releaseMemory returns memory to the operating system between invocations, if configured.
+func (w *memoryWatchdog) releaseMemory() {
+	switch w.release {
+	case "gc":
+		runtime.GC()
+	case "free":
+		debug.FreeOSMemory()
+	}
+}
+
+// memoryLimitExceededResult is returned instead of the result of the handler, if the soft limit was exceeded.
+func memoryLimitExceededResult() *Result {
+	return &Result{Status: 503, Type: ResultDataTypeText, Data: ErrMemoryLimitExceeded.Error()}
+}
@@ -0,0 +1,75 @@
+package e5e_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"go.anx.io/e5e/v2"
+)
+
+// TestMemoryWatchdog runs in a separate process, since the memory limit applies to the whole process.
+func TestMemoryWatchdog(t *testing.T) {
+	if os.Getenv("E5E_TEST_MEMORY_WATCHDOG") == "1" {
+		e5e.AddHandlerFunc(t.Name(), func(ctx context.Context, r e5e.Request[any, any]) (*e5e.Result, error) {
+			select {
+			case <-ctx.Done():
+				if !errors.Is(context.Cause(ctx), e5e.ErrMemoryLimitExceeded) {
+					return nil, errors.New("unexpected cause")
+				}
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+				return &e5e.Result{Data: "done"}, nil
+			}
+		})
+		os.Args = buildOptions(t.Name())
+		os.Args[3] = "1"
+		e5e.Start(context.Background())
+		os.Exit(0)
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		t.Fatalf("locating the test binary failed: %v", err)
+	}
+	tests := []struct {
+		name   string
+		env    []string
+		result string
+	}{
+		{
+			name:   "soft limit exceeded",
+			env:    []string{"E5E_MEMORY_LIMIT=1MiB", "E5E_MEMORY_RELEASE=free"},
+			result: `{"result":{"status":503,"response_headers":{"X-Request-Id":"test-request-id"},"data":"memory limit exceeded","type":"text"}}`,
+		},
+		{
+			name:   "watchdog disabled",
+			env:    []string{"E5E_MEMORY_LIMIT=1MiB", "E5E_MEMORY_SOFT_LIMIT=0"},
+			result: `{"result":{"response_headers":{"X-Request-Id":"test-request-id"},"data":"done"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(binary, "-test.run=^TestMemoryWatchdog$")
+			cmd.Env = append(append(os.Environ(), "E5E_TEST_MEMORY_WATCHDOG=1"), tt.env...)
+			cmd.Stdin = strings.NewReader(string(defaultPayload) + "\nmetrics\n")
+			var stdout, stderr bytes.Buffer
+			cmd.Stdout, cmd.Stderr = &stdout, &stderr
+			if err := cmd.Run(); err != nil {
+				t.Fatalf("running the helper process failed: %v\n%s", err, stderr.String())
+			}
+
+			frames := strings.Split(stdout.String(), daemonTerminationSequence)
+			Equal(t, 3, len(frames), "number of frames does not match")
+			Equal(t, tt.result, strings.TrimPrefix(frames[0], stdoutTerminationSequence), "result does not match")
+
+			exceeded := strings.Contains(frames[1], `e5e_memory_limit_exceeded_total{entrypoint="TestMemoryWatchdog"} 1`)
+			Equal(t, tt.name == "soft limit exceeded", exceeded, "memory metric does not match")
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package e5e
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+)
+
+// metadata describes the function binary. It's used by e5e for the dashboard.
+type metadata struct {
+	LibraryVersion string               `json:"library_version"`
+	Runtime        string               `json:"runtime"`
+	RuntimeVersion string               `json:"runtime_version"`
+	Features       []string             `json:"features"`
+	Entrypoints    []entrypointMetadata `json:"entrypoints"`
+	Build          *buildMetadata       `json:"build,omitempty"`
+	Limits         limitsMetadata       `json:"limits"`
+	Startup        startupMetadata      `json:"startup"`
+}
+
+// entrypointMetadata describes a registered entrypoint.
+// The types are only known for handlers registered with [AddHandlerFunc] or created by [NewHandlerFactory].
+type entrypointMetadata struct {
+	Name        string `json:"name"`
+	RequestType string `json:"request_type,omitempty"`
+	ContextType string `json:"context_type,omitempty"`
+}
+
+// buildMetadata contains the build information of the binary, as returned by [debug.ReadBuildInfo].
+type buildMetadata struct {
+	Path        string `json:"path"`
+	Version     string `json:"version,omitempty"`
+	VCS         string `json:"vcs,omitempty"`
+	VCSRevision string `json:"vcs_revision,omitempty"`
+	VCSTime     string `json:"vcs_time,omitempty"`
+	VCSModified bool   `json:"vcs_modified,omitempty"`
+}
+
+// limitsMetadata contains the limits the runtime works with.
+type limitsMetadata struct {
+	MaxRequestBytes      int     `json:"max_request_bytes"`
+	MemoryLimitBytes     int64   `json:"memory_limit_bytes,omitempty"`
+	MemorySoftLimitBytes int64   `json:"memory_soft_limit_bytes,omitempty"`
+	MaxProcs             int     `json:"max_procs"`
+	IdleTimeoutSeconds   float64 `json:"idle_timeout_seconds,omitempty"`
+	MaxInvocations       int     `json:"max_invocations,omitempty"`
+	MaxRSSBytes          int64   `json:"max_rss_bytes,omitempty"`
+}
+
+// startupMetadata describes the cold start of the binary, see [IsColdStart].
+// The hooks registered with [OnInit] are not run when writing the metadata, so only their number is known.
+type startupMetadata struct {
+	UntilStartSeconds float64 `json:"until_start_seconds"`
+	InitHooks         int     `json:"init_hooks"`
+}
+
+// writeMetadata writes the metadata of the global mux to [os.Stdout].
+// It's called if the binary is started with the single argument "metadata", after all handlers were registered.
+func writeMetadata() {
+	metadataBytes, err := json.Marshal(globalMux.metadata())
+	if err != nil {
+		panic(fmt.Errorf("go-e5e: metadata generation failed: %w", err))
+	}
+	_, _ = os.Stdout.Write(metadataBytes)
+}
+
+func (m *mux) metadata() metadata {
+	md := metadata{
+		LibraryVersion: LibraryVersion,
+		Runtime:        "Go",
+		RuntimeVersion: runtime.Version(),
+		Features:       m.features(),
+		Entrypoints:    make([]entrypointMetadata, 0, len(m.handlers)),
+		Build:          readBuildMetadata(),
+		Limits: limitsMetadata{
+			MaxRequestBytes: maxRequestSize,
+			MaxProcs:        runtime.GOMAXPROCS(0),
+		},
+	}
+	for _, phase := range startup.durations() {
+		if phase.name == "until_start" {
+			md.Startup.UntilStartSeconds = phase.duration.Seconds()
+		}
+	}
+	md.Startup.InitHooks = startup.hookCount()
+	md.Limits.MemorySoftLimitBytes = memory.softLimit
+	if recycle, err := recyclingFromEnv(); err == nil {
+		md.Limits.IdleTimeoutSeconds = recycle.idleTimeout.Seconds()
+		md.Limits.MaxInvocations = recycle.maxInvocations
+		md.Limits.MaxRSSBytes = recycle.maxRSS
+	}
+	md.Limits.MemoryLimitBytes = memory.limit
+	if limit := debug.SetMemoryLimit(-1); md.Limits.MemoryLimitBytes == 0 && limit != math.MaxInt64 {
+		md.Limits.MemoryLimitBytes = limit
+	}
+
+	for _, name := range sortedKeys(m.handlers) {
+		entrypoint := entrypointMetadata{Name: name}
+		if typed, ok := m.handlers[name].(interface{ requestTypes() (string, string) }); ok {
+			entrypoint.RequestType, entrypoint.ContextType = typed.requestTypes()
+		}
+		md.Entrypoints = append(md.Entrypoints, entrypoint)
+	}
+	return md
+}
+
+// features returns the features of the runtime. Features that depend on the configuration
+// are only listed if they are enabled.
+func (m *mux) features() []string {
+	features := []string{"keepalive", "logging", "request_id", "metrics", "profiling"}
+	if len(m.middlewares) > 0 {
+		features = append(features, "middleware")
+	}
+	if memory.softLimit > 0 {
+		features = append(features, "memory_watchdog")
+	}
+	if tracer.enabled() {
+		features = append(features, "tracing")
+	}
+	if os.Getenv(envMetricsPushURL) != "" {
+		features = append(features, "metrics_push")
+	}
+	if os.Getenv(envRecordFile) != "" {
+		features = append(features, "recording")
+	}
+	if recycle, _ := recyclingFromEnv(); recycle != (recycling{}) {
+		features = append(features, "recycling")
+	}
+	if dumper, _ := debugDumperFromEnv(); dumper != nil {
+		features = append(features, "debug_dump")
+	}
+	return features
+}
+
+// readBuildMetadata returns the build information of the binary, or nil if it's not available.
+func readBuildMetadata() *buildMetadata {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	build := &buildMetadata{Path: info.Main.Path, Version: info.Main.Version}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs":
+			build.VCS = setting.Value
+		case "vcs.revision":
+			build.VCSRevision = setting.Value
+		case "vcs.time":
+			build.VCSTime = setting.Value
+		case "vcs.modified":
+			build.VCSModified = setting.Value == "true"
+		}
+	}
+	return build
+}
@@ -0,0 +1,92 @@
+package e5e_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestMetadata(t *testing.T) {
+	e5e.AddHandlerFunc(t.Name(), func(ctx context.Context, r e5e.Request[IntegrationTestPayload, IntegrationTestContext]) (*e5e.Result, error) {
+		return nil, nil
+	})
+	e5e.AddHandler(t.Name()+"_raw", e5e.HandlerFactoryFunc(func(ctx context.Context, payload []byte) (*e5e.Result, error) {
+		return nil, nil
+	}))
+
+	stdio := redirectStdio(t, "")
+	os.Args = []string{"test-binary", "metadata"}
+	e5e.Start(context.Background())
+	stdout, _ := stdio.ReadAndRestore()
+
+	var metadata struct {
+		LibraryVersion string   `json:"library_version"`
+		Runtime        string   `json:"runtime"`
+		RuntimeVersion string   `json:"runtime_version"`
+		Features       []string `json:"features"`
+		Entrypoints    []struct {
+			Name        string `json:"name"`
+			RequestType string `json:"request_type"`
+			ContextType string `json:"context_type"`
+		} `json:"entrypoints"`
+		Build struct {
+			Path string `json:"path"`
+		} `json:"build"`
+		Limits struct {
+			MaxRequestBytes int `json:"max_request_bytes"`
+			MaxProcs        int `json:"max_procs"`
+		} `json:"limits"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &metadata); err != nil {
+		t.Fatalf("metadata %q is not valid JSON: %v", stdout, err)
+	}
+
+	Equal(t, e5e.LibraryVersion, metadata.LibraryVersion, "library version does not match")
+	Equal(t, "Go", metadata.Runtime, "runtime does not match")
+	Equal(t, runtime.Version(), metadata.RuntimeVersion, "runtime version does not match")
+	Equal(t, "keepalive", metadata.Features[0], "keepalive must be the first feature")
+	Equal(t, true, strings.HasPrefix(metadata.Build.Path, "go.anx.io/e5e/v2"), "build path does not match: "+metadata.Build.Path)
+	Equal(t, 1<<30, metadata.Limits.MaxRequestBytes, "max request size does not match")
+	Equal(t, runtime.GOMAXPROCS(0), metadata.Limits.MaxProcs, "max procs does not match")
+
+	types := make(map[string][2]string)
+	for _, entrypoint := range metadata.Entrypoints {
+		types[entrypoint.Name] = [2]string{entrypoint.RequestType, entrypoint.ContextType}
+	}
+	Equal(t, [2]string{"e5e_test.IntegrationTestPayload", "e5e_test.IntegrationTestContext"}, types[t.Name()], "types do not match")
+	raw, ok := types[t.Name()+"_raw"]
+	Equal(t, true, ok, "raw entrypoint is missing")
+	Equal(t, [2]string{}, raw, "raw entrypoint must not have types")
+}
+
+func TestMetadataWithInvalidConfiguration(t *testing.T) {
+	t.Setenv("E5E_LOG_LEVEL", "invalid")
+	t.Setenv("E5E_MEMORY_LIMIT", "invalid")
+	e5e.AddHandlerFunc(t.Name(), func(ctx context.Context, r e5e.Request[any, any]) (*e5e.Result, error) {
+		return nil, nil
+	})
+
+	stdio := redirectStdio(t, "")
+	os.Args = []string{"test-binary", "metadata"}
+	e5e.Start(context.Background())
+	stdout, _ := stdio.ReadAndRestore()
+
+	var metadata struct {
+		Entrypoints []struct {
+			Name string `json:"name"`
+		} `json:"entrypoints"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &metadata); err != nil {
+		t.Fatalf("metadata %q is not valid JSON: %v", stdout, err)
+	}
+	var found bool
+	for _, entrypoint := range metadata.Entrypoints {
+		found = found || entrypoint.Name == t.Name()
+	}
+	Equal(t, true, found, "entrypoint is missing")
+}
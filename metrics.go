@@ -0,0 +1,250 @@
+package e5e
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables that control pushing the metrics, see [WriteMetrics].
+const (
+	envMetricsPushURL      = "E5E_METRICS_PUSH_URL"
+	envMetricsPushInterval = "E5E_METRICS_PUSH_INTERVAL"
+)
+
+// defaultMetricsPushInterval is the interval the metrics are pushed in, if none is configured.
+const defaultMetricsPushInterval = 15 * time.Second
+
+var (
+	durationBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	sizeBuckets     = []float64{256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20}
+)
+
+// invocationMetrics collects the metrics of all invocations.
+var invocationMetrics = &metrics{entrypoints: make(map[string]*entrypointMetrics)}
+
+type metrics struct {
+	mu          sync.Mutex
+	entrypoints map[string]*entrypointMetrics
+}
+
+// entrypointMetrics contains the metrics of a single entrypoint.
+type entrypointMetrics struct {
+	invocations    uint64
+	errors         uint64
+	panics         uint64
+	memoryExceeded uint64
+	duration       histogram
+	decode         histogram
+	requestBytes   histogram
+	responseBytes  histogram
+}
+
+// histogram counts observations in buckets with the given upper bounds.
+type histogram struct {
+	bounds []float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(h.bounds))
+	}
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// update calls fn with the metrics of the entrypoint, while holding the lock.
+func (m *metrics) update(entrypoint string, fn func(e *entrypointMetrics)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entrypoints[entrypoint]
+	if !ok {
+		e = &entrypointMetrics{
+			duration:      histogram{bounds: durationBuckets},
+			decode:        histogram{bounds: durationBuckets},
+			requestBytes:  histogram{bounds: sizeBuckets},
+			responseBytes: histogram{bounds: sizeBuckets},
+		}
+		m.entrypoints[entrypoint] = e
+	}
+	fn(e)
+}
+
+// decodeStatsContextKey is the context key of the entrypoint whose decoding time is measured.
+type decodeStatsContextKey struct{}
+
+// observeDecodeDuration records the time it took to decode the request of the current invocation.
+func observeDecodeDuration(ctx context.Context, d time.Duration) {
+	if entrypoint, ok := ctx.Value(decodeStatsContextKey{}).(string); ok {
+		invocationMetrics.update(entrypoint, func(e *entrypointMetrics) { e.decode.observe(d.Seconds()) })
+	}
+}
+
+// observeResponseSize records the size of the response written by the runtime.
+func observeResponseSize(entrypoint string, size int) {
+	invocationMetrics.update(entrypoint, func(e *entrypointMetrics) { e.responseBytes.observe(float64(size)) })
+}
+
+// WriteMetrics writes the metrics of all invocations in the Prometheus text exposition format to w.
+//
+// The following metrics are collected per entrypoint:
+//
+//   - e5e_invocations_total: the number of invocations.
+//   - e5e_invocation_errors_total: the number of invocations whose handler returned an error.
+//   - e5e_invocation_panics_total: the number of invocations whose handler panicked.
+//   - e5e_memory_limit_exceeded_total: the number of invocations that were cancelled, see [ErrMemoryLimitExceeded].
+//   - e5e_invocation_duration_seconds: a histogram of the duration of the invocations.
+//   - e5e_request_decode_duration_seconds: a histogram of the time it took to decode the requests.
+//   - e5e_request_size_bytes: a histogram of the size of the request envelopes.
+//   - e5e_response_size_bytes: a histogram of the size of the responses written by the runtime.
+//
+// Further, e5e_startup_duration_seconds contains the duration of the phases of the cold start, see [IsColdStart].
+//
+// In keepalive mode, the metrics are also returned by the runtime if the line "metrics" is sent instead of an event.
+// Further, the metrics are pushed to the URL given by the environment variable E5E_METRICS_PUSH_URL, e.g. of
+// a Prometheus Pushgateway, in the interval given by E5E_METRICS_PUSH_INTERVAL (default "15s") and on shutdown.
+func WriteMetrics(w io.Writer) error {
+	return invocationMetrics.write(w)
+}
+
+func (m *metrics) write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entrypoints := sortedKeys(m.entrypoints)
+	var buf bytes.Buffer
+	counter := func(name, help string, value func(e *entrypointMetrics) uint64) {
+		_, _ = fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		for _, entrypoint := range entrypoints {
+			_, _ = fmt.Fprintf(&buf, "%s{entrypoint=\"%s\"} %d\n", name, escapeLabel(entrypoint), value(m.entrypoints[entrypoint]))
+		}
+	}
+	hist := func(name, help string, value func(e *entrypointMetrics) *histogram) {
+		_, _ = fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+		for _, entrypoint := range entrypoints {
+			h, label := value(m.entrypoints[entrypoint]), escapeLabel(entrypoint)
+			var cumulative uint64
+			for i, bound := range h.bounds {
+				if h.counts != nil {
+					cumulative += h.counts[i]
+				}
+				_, _ = fmt.Fprintf(&buf, "%s_bucket{entrypoint=\"%s\",le=\"%s\"} %d\n", name, label, formatFloat(bound), cumulative)
+			}
+			_, _ = fmt.Fprintf(&buf, "%s_bucket{entrypoint=\"%s\",le=\"+Inf\"} %d\n", name, label, h.count)
+			_, _ = fmt.Fprintf(&buf, "%s_sum{entrypoint=\"%s\"} %s\n", name, label, formatFloat(h.sum))
+			_, _ = fmt.Fprintf(&buf, "%s_count{entrypoint=\"%s\"} %d\n", name, label, h.count)
+		}
+	}
+
+	counter("e5e_invocations_total", "Number of invocations.", func(e *entrypointMetrics) uint64 { return e.invocations })
+	counter("e5e_invocation_errors_total", "Number of invocations whose handler returned an error.", func(e *entrypointMetrics) uint64 { return e.errors })
+	counter("e5e_invocation_panics_total", "Number of invocations whose handler panicked.", func(e *entrypointMetrics) uint64 { return e.panics })
+	counter("e5e_memory_limit_exceeded_total", "Number of invocations that were cancelled because the memory limit was exceeded.", func(e *entrypointMetrics) uint64 { return e.memoryExceeded })
+	hist("e5e_invocation_duration_seconds", "Duration of the invocations.", func(e *entrypointMetrics) *histogram { return &e.duration })
+	hist("e5e_request_decode_duration_seconds", "Time it took to decode the requests.", func(e *entrypointMetrics) *histogram { return &e.decode })
+	hist("e5e_request_size_bytes", "Size of the request envelopes.", func(e *entrypointMetrics) *histogram { return &e.requestBytes })
+	hist("e5e_response_size_bytes", "Size of the responses written by the runtime.", func(e *entrypointMetrics) *histogram { return &e.responseBytes })
+
+	_, _ = fmt.Fprintf(&buf, "# HELP e5e_startup_duration_seconds Duration of the phases of the cold start.\n")
+	_, _ = fmt.Fprintf(&buf, "# TYPE e5e_startup_duration_seconds gauge\n")
+	for _, phase := range startup.durations() {
+		_, _ = fmt.Fprintf(&buf, "e5e_startup_duration_seconds{phase=\"%s\"} %s\n", phase.name, formatFloat(phase.duration.Seconds()))
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+
+// metricsPusher pushes the metrics to a URL in a fixed interval.
+type metricsPusher struct {
+	url      string
+	interval time.Duration
+	client   *http.Client
+	stopped  chan struct{}
+	done     chan struct{}
+}
+
+// metricsPusherFromEnv returns a pusher, if pushing is enabled by the environment, or nil otherwise.
+func metricsPusherFromEnv() (*metricsPusher, error) {
+	url := os.Getenv(envMetricsPushURL)
+	if url == "" {
+		return nil, nil
+	}
+	interval := defaultMetricsPushInterval
+	if v := os.Getenv(envMetricsPushInterval); v != "" {
+		var err error
+		if interval, err = time.ParseDuration(v); err != nil || interval <= 0 {
+			return nil, fmt.Errorf("go-e5e: invalid %s %q", envMetricsPushInterval, v)
+		}
+	}
+	return &metricsPusher{url: url, interval: interval, client: &http.Client{Timeout: 5 * time.Second}}, nil
+}
+
+// start pushes the metrics in the background, until stop is called.
+func (p *metricsPusher) start() {
+	p.stopped, p.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.push()
+			case <-p.stopped:
+				return
+			}
+		}
+	}()
+}
+
+// stop stops pushing in the background and pushes the metrics a last time.
+func (p *metricsPusher) stop() {
+	close(p.stopped)
+	<-p.done
+	p.push()
+}
+
+// push sends the metrics. Failures are logged.
+func (p *metricsPusher) push() {
+	var buf bytes.Buffer
+	_ = WriteMetrics(&buf)
+	req, err := http.NewRequest(http.MethodPost, p.url, &buf)
+	if err != nil {
+		logger.Warn("pushing metrics failed", "error", err.Error())
+		return
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		logger.Warn("pushing metrics failed", "error", err.Error())
+		return
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		logger.Warn("pushing metrics failed", "status", resp.StatusCode)
+	}
+}
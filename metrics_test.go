@@ -0,0 +1,58 @@
+package e5e_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestMetrics(t *testing.T) {
+	pushed := make(chan string, 1)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		select {
+		case pushed <- string(body):
+		default:
+		}
+	}))
+	defer gateway.Close()
+	t.Setenv("E5E_METRICS_PUSH_URL", gateway.URL+"/metrics/job/e5e")
+
+	e5e.AddHandlerFunc(t.Name(), func(ctx context.Context, r e5e.Request[IntegrationTestPayload, IntegrationTestContext]) (*e5e.Result, error) {
+		if r.Data().A < 0 {
+			return nil, errors.New("negative")
+		}
+		return &e5e.Result{Data: r.Data().A + r.Data().B}, nil
+	})
+
+	stdio := redirectStdio(t, string(defaultPayload)+"\n"+string(defaultPayload)+"\nmetrics\n")
+	os.Args = buildOptions(t.Name())
+	os.Args[3] = "1"
+	e5e.Start(context.Background())
+	stdout, _ := stdio.ReadAndRestore()
+
+	frames := strings.Split(stdout, daemonTerminationSequence)
+	Equal(t, 4, len(frames), "number of frames does not match")
+	exposition := strings.TrimPrefix(frames[2], stdoutTerminationSequence)
+	for _, expected := range []string{
+		"# TYPE e5e_invocations_total counter\n",
+		`e5e_invocations_total{entrypoint="TestMetrics"} 2` + "\n",
+		`e5e_invocation_errors_total{entrypoint="TestMetrics"} 0` + "\n",
+		"# TYPE e5e_invocation_duration_seconds histogram\n",
+		`e5e_invocation_duration_seconds_count{entrypoint="TestMetrics"} 2` + "\n",
+		`e5e_request_decode_duration_seconds_count{entrypoint="TestMetrics"} 2` + "\n",
+		`e5e_request_size_bytes_bucket{entrypoint="TestMetrics",le="+Inf"} 2` + "\n",
+		`e5e_response_size_bytes_bucket{entrypoint="TestMetrics",le="256"} 2` + "\n",
+	} {
+		Equal(t, true, strings.Contains(exposition, expected), "metrics do not contain "+expected)
+	}
+
+	Equal(t, true, strings.Contains(<-pushed, `e5e_invocations_total{entrypoint="TestMetrics"} 2`), "metrics were not pushed")
+}
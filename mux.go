@@ -4,18 +4,20 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
-	"runtime"
 	"strings"
 	"sync"
+	"time"
 )
 
 // mux defines a container for entrypoints and routes the requests for the given entrypoint
 // to the dedicated handlers.
 type mux struct {
 	handlers    map[string]HandlerFactory
+	middlewares []Middleware
 	stdinReader *bufio.Scanner
 
 	lock sync.Mutex
@@ -23,12 +25,8 @@ type mux struct {
 
 var globalMux = &mux{handlers: make(map[string]HandlerFactory)}
 
-func init() {
-	if len(os.Args) == 2 && os.Args[1] == "metadata" {
-		writeMetadata()
-		os.Exit(0)
-	}
-}
+// maxRequestSize is the maximum size of a single line read from [os.Stdin], which is one request envelope.
+const maxRequestSize = 1024 * 1024 * 1024 // 1 GiB
 
 // Start starts the global mux.
 //
@@ -37,8 +35,39 @@ func init() {
 // Therefore, the developer must ensure, that a respective handler is registered using [AddHandlerFunc]
 // before this function is called.
 //
+// Instead of handling E5E calls, the following commands are supported for local development:
+//
+//   - "metadata" writes a description of the registered entrypoints and the runtime, which is used by e5e.
+//   - "serve [addr]" starts a local development server, see [ListenAndServe].
+//   - "replay <recording file>" replays a recording and reports the differences, see [Replay].
+//   - "invoke <entrypoint> [flags]" executes the handler once with an event built from the flags and prints the result.
+//
+// The command names are only reserved for the number of arguments the commands take,
+// so entrypoints named like a command are still started by e5e with the usual arguments.
+//
 // All runtime errors panic.
 func Start(ctx context.Context) {
+	startup.markStartCalled()
+
+	// The metadata only describes the binary, so it's written even if the configuration is invalid.
+	if command(os.Args) == "metadata" {
+		_ = configure()
+		writeMetadata()
+		return
+	}
+	if err := configure(); err != nil {
+		panic(err)
+	}
+	defer flushLogs()
+
+	defer runShutdownHooks(ctx)
+	if err := startup.runInitHooks(ctx); err != nil {
+		panic(err)
+	}
+	if runCommand(ctx, os.Args) {
+		return
+	}
+
 	args, err := parseArguments(os.Args)
 	if err != nil {
 		panic(err)
@@ -50,6 +79,12 @@ func Start(ctx context.Context) {
 	}
 }
 
+// configure applies the configuration of the runtime from the environment.
+// All settings are applied, even if some of them are invalid, and their errors are returned together.
+func configure() error {
+	return errors.Join(configureLogging(), configureTracing(), configureRequestID(), configureMemory())
+}
+
 // AddHandlerFunc adds the handler for the given entrypoint to the global handler.
 // It panics if the entrypoint was already registered.
 //
@@ -60,15 +95,44 @@ func AddHandlerFunc[T, TContext Data](entrypoint string, fn func(context.Context
 	}
 }
 
+// AddHandler adds the handler factory for the given entrypoint to the global handler.
+// In contrast to [AddHandlerFunc], the factory receives the raw JSON payload of each event.
+// It panics if the entrypoint was already registered.
+func AddHandler(entrypoint string, factory HandlerFactory) {
+	if err := addFactorySafely(globalMux, entrypoint, factory); err != nil {
+		panic(err)
+	}
+}
+
+// Use adds middleware to the global mux, which is applied to the executions of all entrypoints.
+// Middleware is applied in the order it was added, so the first middleware is the outermost one.
+func Use(middleware ...Middleware) {
+	globalMux.middlewares = append(globalMux.middlewares, middleware...)
+}
+
+// Invocation returns a factory that executes the given factory the same way the runtime executes the handler
+// of an entrypoint: with the middleware added by [Use] and the context of an invocation, see [RequestID],
+// [IsColdStart], [Logger] and [Span]. Errors are wrapped with the request ID and the request ID is echoed
+// in the response headers. It's used by the e5etest package to run handlers in-process.
+func Invocation(entrypoint string, factory HandlerFactory) HandlerFactory {
+	return globalMux.wrap(entrypoint, factory)
+}
+
 // addHandlerSafely adds the handler for the given entrypoint to the mux.
 // If there's an error, usually by registering the same entrypoint twice, an error is returned.
 func addHandlerSafely[T, TContext Data](m *mux, entrypoint string, handler Handler[T, TContext]) error {
+	return addFactorySafely(m, entrypoint, NewHandlerFactory(handler))
+}
+
+// addFactorySafely adds the handler factory for the given entrypoint to the mux.
+// If there's an error, usually by registering the same entrypoint twice, an error is returned.
+func addFactorySafely(m *mux, entrypoint string, factory HandlerFactory) error {
 	_, exists := m.handlers[entrypoint]
 	if exists {
 		return fmt.Errorf("entrypoint %q is already registered on this mux", entrypoint)
 	}
 
-	m.handlers[entrypoint] = &typedHandlerFactory[T, TContext]{h: handler}
+	m.handlers[entrypoint] = factory
 	return nil
 }
 
@@ -78,8 +142,8 @@ func addHandlerSafely[T, TContext Data](m *mux, entrypoint string, handler Handl
 //
 // The following rules apply:
 //
-//   - args must contain either two or five elements.
-//   - If it contains two elements, the second element must be `metadata` in order for [options.WriteMetadata] to be true.
+//   - args must contain five elements.
+//   - The argument `metadata` is handled as command before, see [writeMetadata].
 //
 // # Argument order
 //
@@ -113,6 +177,7 @@ func parseArguments(args []string) (options, error) {
 //
 // If [options.KeepAlive] is true, the goroutine is blocked and can be cancelled
 // via the context. It also listens for incoming [syscall.SIGINT] signals and stops gracefully.
+// Further, it stops once one of the recycling limits is reached, see [OnShutdown].
 func (m *mux) Start(ctx context.Context, opts options) error {
 	if _, hasEntrypoint := m.handlers[opts.Entrypoint]; !hasEntrypoint {
 		return InvalidEntrypointError{opts.Entrypoint}
@@ -124,7 +189,7 @@ func (m *mux) Start(ctx context.Context, opts options) error {
 	// Lock the stdin until the supportive goroutine doesn't need it anymore.
 	m.lock.Lock()
 	m.stdinReader = bufio.NewScanner(os.Stdin)
-	m.stdinReader.Buffer([]byte{}, 1024*1024*1024) // 1 GiB
+	m.stdinReader.Buffer([]byte{}, maxRequestSize)
 
 	// Read the lines in the background and cancel the reading with the given context.
 	lineChan := make(chan []byte)
@@ -142,7 +207,13 @@ func (m *mux) Start(ctx context.Context, opts options) error {
 					continue
 				}
 
-				lineChan <- b
+				// The scanner reuses its buffer, so the line is copied before it's handed over.
+				// The runtime might have stopped meanwhile, e.g. for recycling the daemon.
+				select {
+				case lineChan <- append([]byte(nil), b...):
+				case <-ctx.Done():
+					break loop
+				}
 			}
 		}
 
@@ -151,8 +222,52 @@ func (m *mux) Start(ctx context.Context, opts options) error {
 		}
 	}(ctx)
 
-	for line := range lineChan {
+	rec, err := recorderFromEnv()
+	if err != nil {
+		return err
+	}
+	if rec != nil {
+		defer rec.Close()
+	}
+
+	pusher, err := metricsPusherFromEnv()
+	if err != nil {
+		return err
+	}
+	if pusher != nil {
+		pusher.start()
+		defer pusher.stop()
+	}
+	defer profiles.stopAll()
+
+	dumper, err := debugDumperFromEnv()
+	if err != nil {
+		return err
+	}
+
+	recycle, err := recyclingFromEnv()
+	if err != nil {
+		return err
+	}
+	if !opts.KeepAlive {
+		recycle = recycling{}
+	}
+
+	var invocations int
+	for {
+		line, ok := nextLine(lineChan, recycle.idleTimeout)
+		if !ok {
+			break
+		}
+
+		start := time.Now()
 		response, err := m.execute(ctx, line, opts)
+		if rec != nil && !isControlMessage(line, opts) {
+			rec.record(opts.Entrypoint, start, line, response, err)
+		}
+		if dumper != nil && !isControlMessage(line, opts) {
+			dumper.dump(opts.Entrypoint, line, response, err)
+		}
 		if err != nil {
 			return fmt.Errorf("go-e5e: executing handler: %w", err)
 		}
@@ -167,54 +282,91 @@ func (m *mux) Start(ctx context.Context, opts options) error {
 
 		// Print execution termination signals
 		_, _ = fmt.Fprint(os.Stdout, opts.DaemonExecutionTerminationSequence)
-		_, _ = fmt.Fprint(os.Stderr, opts.DaemonExecutionTerminationSequence)
+		logOutput.WriteAfterFlush(os.Stderr, opts.DaemonExecutionTerminationSequence)
+		memory.releaseMemory()
+
+		if !isControlMessage(line, opts) {
+			invocations++
+		}
+		if reason := recycle.reason(invocations); reason != "" {
+			logger.Info("stopping the daemon for recycling", "reason", reason, "invocations", invocations)
+			break
+		}
 	}
 
 	return nil
 }
 
+// factory returns the handler factory for the given entrypoint, wrapped with all registered middleware
+// and the setup of the invocation context. The entrypoint must be registered.
+func (m *mux) factory(entrypoint string) HandlerFactory {
+	return m.wrap(entrypoint, m.handlers[entrypoint])
+}
+
+// wrap applies the middleware of the mux to the factory and wraps it with the invocation of the given entrypoint.
+func (m *mux) wrap(entrypoint string, factory HandlerFactory) HandlerFactory {
+	for i := len(m.middlewares) - 1; i >= 0; i-- {
+		factory = m.middlewares[i](factory)
+	}
+	return withInvocation(entrypoint, factory)
+}
+
+// isControlMessage returns true if the line is not an event, but a message to the runtime itself, like "ping".
+func isControlMessage(line []byte, opts options) bool {
+	if !opts.KeepAlive {
+		return false
+	}
+	switch string(line) {
+	case "ping", "metrics":
+		return true
+	}
+	return isProfileMessage(string(line))
+}
+
 // execute reads a line from the input, parses it and returns the response that should be written.
 func (m *mux) execute(ctx context.Context, payload []byte, opts options) (string, error) {
-	if string(payload) == "ping" && opts.KeepAlive {
-		return "pong", nil
+	if isControlMessage(payload, opts) {
+		return executeControlMessage(string(payload))
 	}
 
-	factory := m.handlers[opts.Entrypoint]
-	res, err := factory.Execute(ctx, payload)
+	res, err := m.factory(opts.Entrypoint).Execute(ctx, payload)
 	if err != nil {
 		return "", fmt.Errorf("handler execution: %w", err)
 	}
 
-	wrapped := struct {
-		Result *Result `json:"result"`
-	}{Result: res}
-
-	resp, err := json.Marshal(wrapped)
+	resp, err := MarshalResult(res)
 	if err != nil {
-		return "", fmt.Errorf("marshalling response: %w", err)
+		return "", err
 	}
+	observeResponseSize(opts.Entrypoint, len(resp))
 
 	return string(resp), nil
 }
 
-// write the metadata that's used by e5e for the dashboard to [os.Stdout]
-func writeMetadata() {
-	type metadata struct {
-		LibraryVersion string   `json:"library_version"`
-		Runtime        string   `json:"runtime"`
-		RuntimeVersion string   `json:"runtime_version"`
-		Features       []string `json:"features"`
+// executeControlMessage returns the response to a control message.
+func executeControlMessage(message string) (string, error) {
+	switch {
+	case isProfileMessage(message):
+		return executeProfileMessage(message)
+	case message == "metrics":
+		var buf strings.Builder
+		err := WriteMetrics(&buf)
+		return buf.String(), err
+	default:
+		return "pong", nil
 	}
+}
 
-	metadataInstance := metadata{
-		LibraryVersion: LibraryVersion,
-		Runtime:        "Go",
-		RuntimeVersion: runtime.Version(),
-		Features:       []string{"keepalive"},
-	}
-	metadataBytes, err := json.Marshal(metadataInstance)
+// MarshalResult returns the JSON encoding of the result, exactly as it is written by the runtime
+// to [os.Stdout] after the handler returned.
+func MarshalResult(res *Result) ([]byte, error) {
+	wrapped := struct {
+		Result *Result `json:"result"`
+	}{Result: res}
+
+	resp, err := json.Marshal(wrapped)
 	if err != nil {
-		panic(fmt.Errorf("go-e5e: metadata generation failed: %w", err))
+		return nil, fmt.Errorf("marshalling response: %w", err)
 	}
-	_, _ = os.Stdout.Write(metadataBytes)
+	return resp, nil
 }
@@ -52,7 +52,8 @@ var (
 				"b": 3,
 			},
 			"request_headers": map[string]string{
-				"test-header": "test-header-value",
+				"test-header":  "test-header-value",
+				"X-Request-Id": "test-request-id",
 			},
 			"type": "object",
 		},
@@ -71,7 +72,8 @@ var (
 				"test-param": {"a", "b"},
 			},
 			RequestHeaders: map[string]string{
-				"test-header": "test-header-value",
+				"test-header":  "test-header-value",
+				"X-Request-Id": "test-request-id",
 			},
 			Type: e5e.EventDataTypeObject,
 			Data: IntegrationTestPayload{A: 2, B: 3},
@@ -119,14 +121,14 @@ func TestResponse(t *testing.T) {
 			handler: func(*testing.T, e5e.Request[IntegrationTestPayload, IntegrationTestContext]) (*e5e.Result, error) {
 				return &e5e.Result{Data: nil}, nil
 			},
-			result: `{"result":{"data":null}}`,
+			result: `{"result":{"response_headers":{"X-Request-Id":"test-request-id"},"data":null}}`,
 		},
 		{
 			name: "nil result",
 			handler: func(*testing.T, e5e.Request[IntegrationTestPayload, IntegrationTestContext]) (*e5e.Result, error) {
 				return &e5e.Result{Status: 200}, nil
 			},
-			result: `{"result":{"status":200,"data":null}}`,
+			result: `{"result":{"status":200,"response_headers":{"X-Request-Id":"test-request-id"},"data":null}}`,
 		},
 		{
 			name:   "print stdout",
@@ -144,7 +146,7 @@ func TestResponse(t *testing.T) {
 			handler: func(t *testing.T, r e5e.Request[IntegrationTestPayload, IntegrationTestContext]) (*e5e.Result, error) {
 				return &e5e.Result{Data: r.Data().A + r.Data().B}, nil
 			},
-			result: `{"result":{"data":5}}`,
+			result: `{"result":{"response_headers":{"X-Request-Id":"test-request-id"},"data":5}}`,
 		},
 		{
 			name: "request contains all keys and values",
@@ -152,7 +154,7 @@ func TestResponse(t *testing.T) {
 				DeepEqual(t, r, expectedRequest, "request does not match")
 				return &e5e.Result{}, nil
 			},
-			result: `{"result":{"data":null}}`,
+			result: `{"result":{"response_headers":{"X-Request-Id":"test-request-id"},"data":null}}`,
 		},
 	}
 	for _, tt := range tests {
@@ -183,6 +185,7 @@ func TestErrors(t *testing.T) {
 		entrypoint string
 		handler    testHandlerFunc
 		error      error
+		message    string
 	}{
 		{
 			name:       "invalid entrypoint",
@@ -194,6 +197,7 @@ func TestErrors(t *testing.T) {
 			handler: func(t *testing.T, r e5e.Request[IntegrationTestPayload, IntegrationTestContext]) (*e5e.Result, error) {
 				return nil, errors.New("error")
 			},
+			message: "request test-request-id: error",
 		},
 		{
 			name: "invalid result (infinity)",
@@ -222,6 +226,9 @@ func TestErrors(t *testing.T) {
 				if tt.error != nil {
 					Equal(t, tt.error.Error(), err.Error(), "errors do not match")
 				}
+				if tt.message != "" {
+					Equal(t, true, strings.HasSuffix(err.Error(), tt.message), "error does not contain the request ID: "+err.Error())
+				}
 			}()
 
 			entrypoint := t.Name()
@@ -268,9 +275,9 @@ func TestKeepAlive(t *testing.T) {
 	expectedOutputs := []string{
 		"pong",
 		"pong",
-		`{"result":{"data":5}}`,
+		`{"result":{"response_headers":{"X-Request-Id":"test-request-id"},"data":5}}`,
 		"pong",
-		`{"result":{"data":5}}`,
+		`{"result":{"response_headers":{"X-Request-Id":"test-request-id"},"data":5}}`,
 	}
 	var expectedStdout strings.Builder
 	for _, v := range expectedOutputs {
@@ -318,3 +325,21 @@ func DeepEqual(t *testing.T, expected interface{}, actual interface{}, message s
 		t.Fatalf("%s:\n\tgot:\t%+v\n\twanted:\t%+v", message, actual, expected)
 	}
 }
+
+func TestEntrypointsNamedLikeCommands(t *testing.T) {
+	for _, entrypoint := range []string{"serve", "replay", "invoke"} {
+		t.Run(entrypoint, func(t *testing.T) {
+			e5e.AddHandlerFunc(entrypoint, func(ctx context.Context, r e5e.Request[IntegrationTestPayload, any]) (*e5e.Result, error) {
+				return &e5e.Result{Data: entrypoint}, nil
+			})
+
+			stdio := redirectStdio(t, string(defaultPayload))
+			os.Args = buildOptions(entrypoint)
+			e5e.Start(context.Background())
+			stdout, _ := stdio.ReadAndRestore()
+
+			expected := stdoutTerminationSequence + `{"result":{"response_headers":{"X-Request-Id":"test-request-id"},"data":"` + entrypoint + `"}}`
+			Equal(t, expected, stdout, "stdout does not match")
+		})
+	}
+}
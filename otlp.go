@@ -0,0 +1,189 @@
+package e5e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// OTLPExporter is a [SpanExporter] that sends spans to an OpenTelemetry collector,
+// using the OTLP/HTTP protocol with JSON encoding. Use [NewOTLPExporter] to create one.
+type OTLPExporter struct {
+	// The URL the spans are posted to, e.g. "http://localhost:4318/v1/traces".
+	Endpoint string
+
+	// Additional request headers, e.g. for authentication.
+	Headers map[string]string
+
+	// The "service.name" resource attribute of the spans.
+	ServiceName string
+
+	// The client used for sending the spans.
+	Client *http.Client
+}
+
+// NewOTLPExporter returns an exporter that posts the spans to the given endpoint.
+// The service name is read from OTEL_SERVICE_NAME and defaults to the name of the binary.
+func NewOTLPExporter(endpoint string) *OTLPExporter {
+	return &OTLPExporter{
+		Endpoint:    endpoint,
+		Headers:     make(map[string]string),
+		ServiceName: serviceName(),
+		Client:      http.DefaultClient,
+	}
+}
+
+// ExportSpans implements [SpanExporter].
+func (e *OTLPExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(otlpRequest(e.ServiceName, spans))
+	if err != nil {
+		return fmt.Errorf("encoding spans: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending spans: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("sending spans: collector responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// StderrExporter is a [SpanExporter] that writes each span as JSON line to [os.Stderr],
+// alongside the logs written by [Logger]. Use [NewStderrExporter] to create one.
+type StderrExporter struct {
+	// The "service.name" resource attribute of the spans.
+	ServiceName string
+}
+
+// NewStderrExporter returns an exporter that writes the spans to [os.Stderr].
+func NewStderrExporter() *StderrExporter {
+	return &StderrExporter{ServiceName: serviceName()}
+}
+
+// ExportSpans implements [SpanExporter].
+func (e *StderrExporter) ExportSpans(_ context.Context, spans []SpanData) error {
+	for _, span := range spans {
+		line, err := json.Marshal(otlpRequest(e.ServiceName, []SpanData{span}))
+		if err != nil {
+			return fmt.Errorf("encoding span: %w", err)
+		}
+		_, _ = logOutput.Write(append(line, '\n'))
+	}
+	return nil
+}
+
+// The following types describe the JSON encoding of OTLP, see
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto
+
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	TraceState        string          `json:"traceState,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+// otlpStatus has the code 0 (unset) or 2 (error).
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func otlpRequest(serviceName string, spans []SpanData) otlpExportRequest {
+	resource := otlpResourceSpans{ScopeSpans: make([]otlpScopeSpans, 1)}
+	resource.Resource.Attributes = []otlpAttribute{otlpAttributeOf("service.name", serviceName)}
+	scope := &resource.ScopeSpans[0]
+	scope.Scope.Name = "go.anx.io/e5e/v2"
+	scope.Scope.Version = LibraryVersion
+
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.SpanContext.TraceID.String(),
+			SpanID:            s.SpanContext.SpanID.String(),
+			TraceState:        s.SpanContext.TraceState,
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.EndTime.UnixNano(), 10),
+		}
+		if s.ParentSpanID.IsValid() {
+			span.ParentSpanID = s.ParentSpanID.String()
+		}
+		if s.Error != "" {
+			span.Status = otlpStatus{Code: 2, Message: s.Error}
+		}
+		for _, k := range sortedKeys(s.Attributes) {
+			span.Attributes = append(span.Attributes, otlpAttributeOf(k, s.Attributes[k]))
+		}
+		scope.Spans = append(scope.Spans, span)
+	}
+	return otlpExportRequest{ResourceSpans: []otlpResourceSpans{resource}}
+}
+
+// otlpAttributeOf encodes the attribute, 64-bit integers are encoded as string as required by OTLP.
+func otlpAttributeOf(key string, value any) otlpAttribute {
+	var v map[string]any
+	switch value := value.(type) {
+	case string:
+		v = map[string]any{"stringValue": value}
+	case bool:
+		v = map[string]any{"boolValue": value}
+	case int:
+		v = map[string]any{"intValue": strconv.Itoa(value)}
+	case int64:
+		v = map[string]any{"intValue": strconv.FormatInt(value, 10)}
+	case float64:
+		v = map[string]any{"doubleValue": value}
+	default:
+		v = map[string]any{"stringValue": fmt.Sprint(value)}
+	}
+	return otlpAttribute{Key: key, Value: v}
+}
+
+var _ SpanExporter = &OTLPExporter{}
+var _ SpanExporter = &StderrExporter{}
@@ -0,0 +1,166 @@
+package e5e
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// envProfileDir is the environment variable that sets the directory profiles are written to.
+const envProfileDir = "E5E_PROFILE_DIR"
+
+// defaultProfileDuration is the duration of CPU profiles and execution traces, if none is given.
+const defaultProfileDuration = 10 * time.Second
+
+// profiles contains the profiles that are currently running.
+var profiles = &profiler{running: make(map[string]*runningProfile)}
+
+// profiler writes profiles on request of the "profile" control message, see the package documentation.
+type profiler struct {
+	mu      sync.Mutex
+	running map[string]*runningProfile
+}
+
+// runningProfile is a CPU profile or execution trace that is stopped after its duration.
+type runningProfile struct {
+	file  *os.File
+	timer *time.Timer
+	stop  func()
+}
+
+// profileAck is the response to a "profile" control message.
+type profileAck struct {
+	Profile  string `json:"profile,omitempty"`
+	File     string `json:"file,omitempty"`
+	Duration string `json:"duration,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// isProfileMessage returns true if the line is a "profile" control message.
+func isProfileMessage(line string) bool {
+	return line == "profile" || strings.HasPrefix(line, "profile ")
+}
+
+// executeProfileMessage starts the requested profile and returns the acknowledgement.
+// Invalid messages are acknowledged with an error.
+func executeProfileMessage(message string) (string, error) {
+	var ack profileAck
+	if file, duration, err := profiles.start(strings.Fields(message)[1:]); err != nil {
+		ack.Error = err.Error()
+	} else {
+		ack.Profile, ack.File = strings.Fields(message)[1], file
+		if duration > 0 {
+			ack.Duration = duration.String()
+		}
+	}
+	encoded, err := json.Marshal(ack)
+	return string(encoded), err
+}
+
+// start writes the profile given by the arguments of the control message and returns the path of the file.
+// For CPU profiles and traces, the returned duration is the time until the profile is complete.
+func (p *profiler) start(args []string) (string, time.Duration, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return "", 0, fmt.Errorf("usage: profile <cpu|trace|name> [duration]")
+	}
+	kind := args[0]
+	timed := kind == "cpu" || kind == "trace"
+	if !timed && (pprof.Lookup(kind) == nil || len(args) > 1) {
+		return "", 0, fmt.Errorf("unknown profile %q", strings.Join(args, " "))
+	}
+
+	duration := defaultProfileDuration
+	if timed && len(args) > 1 {
+		var err error
+		if duration, err = time.ParseDuration(args[1]); err != nil || duration <= 0 {
+			return "", 0, fmt.Errorf("invalid duration %q", args[1])
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.running[kind]; ok {
+		return "", 0, fmt.Errorf("%s profile is already running", kind)
+	}
+
+	f, err := createProfileFile(kind)
+	if err != nil {
+		return "", 0, err
+	}
+	if !timed {
+		if kind == "heap" {
+			runtime.GC()
+		}
+		err = pprof.Lookup(kind).WriteTo(f, 0)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return f.Name(), 0, err
+	}
+
+	stop := pprof.StopCPUProfile
+	if kind == "trace" {
+		err, stop = trace.Start(f), trace.Stop
+	} else {
+		err = pprof.StartCPUProfile(f)
+	}
+	if err != nil {
+		_ = f.Close()
+		return "", 0, err
+	}
+	p.running[kind] = &runningProfile{
+		file:  f,
+		timer: time.AfterFunc(duration, func() { p.stop(kind) }),
+		stop:  stop,
+	}
+	return f.Name(), duration, nil
+}
+
+// stop completes the running profile of the given kind.
+func (p *profiler) stop(kind string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r, ok := p.running[kind]
+	if !ok {
+		return
+	}
+	delete(p.running, kind)
+	r.timer.Stop()
+	r.stop()
+	_ = r.file.Close()
+}
+
+// stopAll completes all running profiles, e.g. before the runtime stops.
+func (p *profiler) stopAll() {
+	for _, kind := range []string{"cpu", "trace"} {
+		p.stop(kind)
+	}
+}
+
+// createProfileFile creates the file for a new profile in the profile directory.
+func createProfileFile(kind string) (*os.File, error) {
+	dir := os.Getenv(envProfileDir)
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("creating profile directory: %w", err)
+	}
+	ext := ".pprof"
+	if kind == "trace" {
+		ext = ".out"
+	}
+	name := fmt.Sprintf("%s-%s%s", kind, time.Now().UTC().Format("20060102T150405.000Z"), ext)
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("creating profile file: %w", err)
+	}
+	return f, nil
+}
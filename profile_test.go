@@ -0,0 +1,66 @@
+package e5e_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestProfiling(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "profiles")
+	t.Setenv("E5E_PROFILE_DIR", dir)
+
+	var calls int
+	e5e.AddHandlerFunc(t.Name(), func(ctx context.Context, r e5e.Request[IntegrationTestPayload, IntegrationTestContext]) (*e5e.Result, error) {
+		calls++
+		return &e5e.Result{Data: r.Data().A + r.Data().B}, nil
+	})
+
+	messages := []string{"profile heap", "profile cpu 1h", "profile cpu", "profile goroutine", "profile unknown", "profile trace 0s"}
+	stdio := redirectStdio(t, strings.Join(messages, "\n")+"\n"+string(defaultPayload)+"\n")
+	os.Args = buildOptions(t.Name())
+	os.Args[3] = "1"
+	e5e.Start(context.Background())
+	stdout, _ := stdio.ReadAndRestore()
+
+	Equal(t, 1, calls, "profile messages must not reach the handler")
+	frames := strings.Split(stdout, daemonTerminationSequence)
+	Equal(t, len(messages)+2, len(frames), "number of frames does not match")
+
+	type ack struct {
+		Profile  string `json:"profile"`
+		File     string `json:"file"`
+		Duration string `json:"duration"`
+		Error    string `json:"error"`
+	}
+	var acks []ack
+	for _, frame := range frames[:len(messages)] {
+		var a ack
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(frame, stdoutTerminationSequence)), &a); err != nil {
+			t.Fatalf("acknowledgement %q is not valid JSON: %v", frame, err)
+		}
+		acks = append(acks, a)
+	}
+
+	Equal(t, "heap", acks[0].Profile, "heap profile was not acknowledged")
+	Equal(t, "cpu", acks[1].Profile, "cpu profile was not acknowledged")
+	Equal(t, "1h0m0s", acks[1].Duration, "duration does not match")
+	Equal(t, "cpu profile is already running", acks[2].Error, "error does not match")
+	Equal(t, "goroutine", acks[3].Profile, "goroutine profile was not acknowledged")
+	Equal(t, `unknown profile "unknown"`, acks[4].Error, "error does not match")
+	Equal(t, `invalid duration "0s"`, acks[5].Error, "error does not match")
+
+	for _, a := range []ack{acks[0], acks[1], acks[3]} {
+		Equal(t, dir, filepath.Dir(a.File), "profile was written to the wrong directory")
+		info, err := os.Stat(a.File)
+		if err != nil {
+			t.Fatalf("profile %s was not written: %v", a.Profile, err)
+		}
+		Equal(t, true, info.Size() > 0, "profile "+a.Profile+" is empty")
+	}
+}
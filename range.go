@@ -0,0 +1,148 @@
+package e5e
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// byteRange is a satisfiable range of bytes inside a file, with an inclusive start and an exclusive end.
+type byteRange struct{ start, end int64 }
+
+func (r byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.end-1, size)
+}
+
+// RangeResult returns a binary result for the given file, honouring the Range request header of the event
+// as defined in RFC 9110, section 14.
+//
+//   - If rangeHeader is empty, invalid or not worth serving partially, the whole file is returned with status 200.
+//   - If it contains a single satisfiable range, that part is returned with status 206 and a Content-Range header.
+//   - If it contains multiple satisfiable ranges, they are returned as a "multipart/byteranges" file with status 206.
+//   - If none of the ranges can be satisfied, status 416 is returned with a Content-Range header containing the file size.
+//
+// The Accept-Ranges response header is set in all cases, so clients know that they can resume downloads.
+func RangeResult(f File, rangeHeader string) *Result {
+	size := int64(len(f.content))
+	headers := map[string]string{"Accept-Ranges": "bytes"}
+
+	ranges, ok := parseRange(rangeHeader, size)
+	if !ok {
+		return &Result{Status: 200, ResponseHeaders: headers, Type: ResultDataTypeBinary, Data: f}
+	}
+	if len(ranges) == 0 {
+		headers["Content-Range"] = fmt.Sprintf("bytes */%d", size)
+		return &Result{Status: 416, ResponseHeaders: headers}
+	}
+
+	partial := File{Type: f.Type, Name: f.Name, ContentType: f.ContentType, Charset: f.Charset}
+	if len(ranges) == 1 {
+		headers["Content-Range"] = ranges[0].contentRange(size)
+		_, _ = partial.Write(f.content[ranges[0].start:ranges[0].end])
+		return &Result{Status: 206, ResponseHeaders: headers, Type: ResultDataTypeBinary, Data: partial}
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, r := range ranges {
+		partHeader := textproto.MIMEHeader{"Content-Range": {r.contentRange(size)}}
+		if f.ContentType != "" {
+			partHeader.Set("Content-Type", f.ContentType)
+		}
+		part, _ := mw.CreatePart(partHeader)
+		_, _ = io.Copy(part, io.NewSectionReader(f, r.start, r.end-r.start))
+	}
+	_ = mw.Close()
+
+	partial.ContentType = "multipart/byteranges; boundary=" + mw.Boundary()
+	partial.Charset = ""
+	_, _ = partial.Write(buf.Bytes())
+	return &Result{Status: 206, ResponseHeaders: headers, Type: ResultDataTypeBinary, Data: partial}
+}
+
+// parseRange parses the given Range header for a file of the given size.
+//
+// If the header should be ignored, because it's empty, invalid, uses an unknown unit or requests
+// more data than the whole file, false is returned. Otherwise, all satisfiable ranges are returned,
+// which might be none.
+func parseRange(header string, size int64) ([]byteRange, bool) {
+	spec, found := cutPrefixFold(strings.TrimSpace(header), "bytes=")
+	if !found {
+		return nil, false
+	}
+
+	var (
+		ranges []byteRange
+		total  int64
+		parsed int
+	)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, found := strings.Cut(part, "-")
+		if !found {
+			return nil, false
+		}
+		parsed++
+		first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+
+		var r byteRange
+		if first == "" {
+			// A suffix range like "-500" requests the last 500 bytes.
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, false
+			}
+			if n == 0 || size == 0 {
+				continue
+			}
+			if n > size {
+				n = size
+			}
+			r = byteRange{start: size - n, end: size}
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, false
+			}
+			end := size
+			if last != "" {
+				inclusiveEnd, err := strconv.ParseInt(last, 10, 64)
+				if err != nil || inclusiveEnd < start {
+					return nil, false
+				}
+				if inclusiveEnd < size {
+					end = inclusiveEnd + 1
+				}
+			}
+			if start >= size {
+				continue
+			}
+			r = byteRange{start: start, end: end}
+		}
+
+		ranges = append(ranges, r)
+		total += r.end - r.start
+	}
+
+	// Just like net/http, we serve the whole file if the ranges are bigger than the file itself,
+	// as it would be a waste of bandwidth otherwise.
+	if parsed == 0 || total > size {
+		return nil, false
+	}
+	return ranges, true
+}
+
+// cutPrefixFold works like strings.CutPrefix, but ignores the case of the prefix.
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
@@ -0,0 +1,100 @@
+package e5e_test
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestRangeResult(t *testing.T) {
+	t.Parallel()
+
+	file := e5e.File{Name: "alphabet.txt"}
+	_ = file.SetPlainText("abcdefghijklmnopqrstuvwxyz")
+
+	tests := []struct {
+		name         string
+		header       string
+		status       int
+		contentRange string
+		content      string
+	}{
+		{name: "no range", status: 200, content: "abcdefghijklmnopqrstuvwxyz"},
+		{name: "unknown unit", header: "items=0-1", status: 200, content: "abcdefghijklmnopqrstuvwxyz"},
+		{name: "invalid syntax", header: "bytes=a-b", status: 200, content: "abcdefghijklmnopqrstuvwxyz"},
+		{name: "empty range set", header: "bytes=", status: 200, content: "abcdefghijklmnopqrstuvwxyz"},
+		{name: "bigger than the file", header: "bytes=0-20,5-25", status: 200, content: "abcdefghijklmnopqrstuvwxyz"},
+		{name: "first bytes", header: "bytes=0-4", status: 206, contentRange: "bytes 0-4/26", content: "abcde"},
+		{name: "open end", header: "bytes=20-", status: 206, contentRange: "bytes 20-25/26", content: "uvwxyz"},
+		{name: "suffix", header: "bytes=-3", status: 206, contentRange: "bytes 23-25/26", content: "xyz"},
+		{name: "end beyond size", header: "Bytes=24-100", status: 206, contentRange: "bytes 24-25/26", content: "yz"},
+		{name: "unsatisfiable", header: "bytes=26-30", status: 416, contentRange: "bytes */26"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			res := e5e.RangeResult(file, tt.header)
+
+			Equal(t, tt.status, res.Status, "status does not match")
+			Equal(t, "bytes", res.ResponseHeaders["Accept-Ranges"], "Accept-Ranges does not match")
+			Equal(t, tt.contentRange, res.ResponseHeaders["Content-Range"], "Content-Range does not match")
+			if tt.status == 416 {
+				DeepEqual(t, nil, res.Data, "data is not empty")
+				return
+			}
+
+			Equal(t, e5e.ResultDataTypeBinary, res.Type, "result type does not match")
+			partial := res.Data.(e5e.File)
+			Equal(t, tt.content, string(partial.Bytes()), "content does not match")
+			Equal(t, int64(len(tt.content)), partial.SizeInBytes, "file size does not match")
+			Equal(t, "text/plain", partial.ContentType, "content type does not match")
+		})
+	}
+
+	t.Run("multiple ranges", func(t *testing.T) {
+		t.Parallel()
+		res := e5e.RangeResult(file, "bytes=0-1, -2")
+		Equal(t, 206, res.Status, "status does not match")
+
+		partial := res.Data.(e5e.File)
+		mediaType, params, err := mime.ParseMediaType(partial.ContentType)
+		if err != nil {
+			t.Fatalf("parsing content type failed: %v", err)
+		}
+		Equal(t, "multipart/byteranges", mediaType, "media type does not match")
+
+		expected := []struct{ contentRange, content string }{
+			{"bytes 0-1/26", "ab"},
+			{"bytes 24-25/26", "yz"},
+		}
+		mr := multipart.NewReader(partial.NewReader(), params["boundary"])
+		for _, e := range expected {
+			part, err := mr.NextPart()
+			if err != nil {
+				t.Fatalf("reading part failed: %v", err)
+			}
+			content, _ := io.ReadAll(part)
+			Equal(t, e.contentRange, part.Header.Get("Content-Range"), "Content-Range of part does not match")
+			Equal(t, "text/plain", part.Header.Get("Content-Type"), "Content-Type of part does not match")
+			Equal(t, e.content, string(content), "content of part does not match")
+		}
+		if _, err := mr.NextPart(); err != io.EOF {
+			t.Errorf("expected exactly two parts, got: %v", err)
+		}
+	})
+	t.Run("file is seekable", func(t *testing.T) {
+		t.Parallel()
+		r := file.NewReader()
+		if _, err := r.Seek(-3, io.SeekEnd); err != nil {
+			t.Fatalf("seeking failed: %v", err)
+		}
+		var buf strings.Builder
+		_, _ = io.Copy(&buf, r)
+		Equal(t, "xyz", buf.String(), "content does not match")
+	})
+}
@@ -0,0 +1,271 @@
+package e5e
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables that control the recording of events, see [Recording].
+const (
+	envRecordFile              = "E5E_RECORD_FILE"
+	envRecordRedactHeaders     = "E5E_RECORD_REDACT_HEADERS"
+	envRecordRedactContextData = "E5E_RECORD_REDACT_CONTEXT_DATA"
+	envRecordRedactPaths       = "E5E_RECORD_REDACT_PATHS"
+)
+
+// Recording is a single line of a recording file.
+//
+// Recording is enabled by setting the environment variable E5E_RECORD_FILE to the path of a file.
+// Each incoming event and the produced response are then appended to this file as one JSON line,
+// which can be fed through the handlers again using [Replay].
+//
+// Sensitive values are redacted before they are written:
+//
+//   - E5E_RECORD_REDACT_HEADERS contains a comma-separated list of request and response headers.
+//     If it is not set, the Authorization, Cookie, Proxy-Authorization and Set-Cookie headers are redacted.
+//   - E5E_RECORD_REDACT_CONTEXT_DATA contains a comma-separated list of keys of the context data, e.g. "api_key".
+//   - E5E_RECORD_REDACT_PATHS contains a comma-separated list of dot-separated JSON paths inside the request
+//     or the response, e.g. "event.data.password,result.data.*.token". A "*" matches every key or array element.
+type Recording struct {
+	// The time the event was received.
+	Time time.Time `json:"time"`
+
+	// The entrypoint that handled the event.
+	Entrypoint string `json:"entrypoint"`
+
+	// The request envelope, as received on [os.Stdin].
+	Request json.RawMessage `json:"request"`
+
+	// The response, as written to [os.Stdout]. Empty, if the handler failed.
+	Response json.RawMessage `json:"response,omitempty"`
+
+	// The error returned by the handler.
+	Error string `json:"error,omitempty"`
+
+	// The duration of the execution in milliseconds.
+	DurationMilliseconds float64 `json:"duration_ms"`
+}
+
+// recorder appends recordings to a file.
+type recorder struct {
+	mu       sync.Mutex
+	w        io.WriteCloser
+	redactor redactor
+}
+
+// recorderFromEnv returns a recorder, if recording is enabled by the environment, or nil otherwise.
+func recorderFromEnv() (*recorder, error) {
+	path := os.Getenv(envRecordFile)
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("go-e5e: opening recording file: %w", err)
+	}
+	return &recorder{w: f, redactor: redactorFromEnv(envRecordRedactHeaders, envRecordRedactContextData, envRecordRedactPaths)}, nil
+}
+
+// record appends a single execution to the recording. Failures are reported on [os.Stderr].
+func (r *recorder) record(entrypoint string, start time.Time, request []byte, response string, err error) {
+	rec := Recording{
+		Time:                 start.UTC(),
+		Entrypoint:           entrypoint,
+		Request:              r.redactor.redact(request),
+		DurationMilliseconds: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		rec.Error = handlerErrorMessage(err)
+	} else {
+		rec.Response = r.redactor.redact([]byte(response))
+	}
+
+	line, marshalErr := json.Marshal(rec)
+	if marshalErr != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "go-e5e: encoding recording failed: %v\n", marshalErr)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, writeErr := r.w.Write(append(line, '\n')); writeErr != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "go-e5e: writing recording failed: %v\n", writeErr)
+	}
+}
+
+func (r *recorder) Close() error { return r.w.Close() }
+
+// ReplayResult contains the outcome of replaying a single [Recording].
+type ReplayResult struct {
+	// The replayed recording.
+	Recording Recording
+
+	// The new response.
+	Response json.RawMessage
+
+	// The error returned by the handler during the replay.
+	Err error
+
+	// The differences between the recorded and the new response, as human-readable lines.
+	// Values that were redacted during the recording and the echoed request ID (see [RequestID]) are not compared.
+	Differences []string
+}
+
+// Equal returns true if the new response matches the recorded one.
+func (r ReplayResult) Equal() bool { return len(r.Differences) == 0 }
+
+// Replay feeds all events of a recording through the registered handlers, including all middleware,
+// and compares the new responses with the recorded ones. See [Recording] on how to create a recording.
+//
+// Replaying is also possible by starting the binary with the arguments "replay <recording file>".
+// Note that redacted values in the recorded requests are passed to the handlers as "[REDACTED]".
+func Replay(ctx context.Context, recording io.Reader) ([]ReplayResult, error) {
+	return globalMux.replay(ctx, recording)
+}
+
+func (m *mux) replay(ctx context.Context, recording io.Reader) ([]ReplayResult, error) {
+	var results []ReplayResult
+	scanner := bufio.NewScanner(recording)
+	scanner.Buffer([]byte{}, 1024*1024*1024) // 1 GiB
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var result ReplayResult
+		if err := json.Unmarshal(scanner.Bytes(), &result.Recording); err != nil {
+			return results, fmt.Errorf("decoding recording %d: %w", len(results)+1, err)
+		}
+
+		if _, ok := m.handlers[result.Recording.Entrypoint]; !ok {
+			result.Err = InvalidEntrypointError{Entrypoint: result.Recording.Entrypoint}
+		} else {
+			var res *Result
+			if res, result.Err = m.factory(result.Recording.Entrypoint).Execute(ctx, result.Recording.Request); result.Err == nil {
+				result.Response, result.Err = MarshalResult(res)
+			}
+		}
+		result.Differences = diffReplay(result)
+		results = append(results, result)
+	}
+	return results, scanner.Err()
+}
+
+// diffReplay compares the recorded and the replayed outcome.
+func diffReplay(r ReplayResult) []string {
+	switch {
+	case r.Recording.Error != "" && r.Err != nil:
+		if replayed := handlerErrorMessage(r.Err); r.Recording.Error != replayed {
+			return []string{fmt.Sprintf("error: recorded %q, replayed %q", r.Recording.Error, replayed)}
+		}
+		return nil
+	case r.Recording.Error != "":
+		return []string{fmt.Sprintf("error: recorded %q, replayed none", r.Recording.Error)}
+	case r.Err != nil:
+		return []string{fmt.Sprintf("error: recorded none, replayed %q", handlerErrorMessage(r.Err))}
+	}
+
+	var recorded, replayed any
+	if err := json.Unmarshal(r.Recording.Response, &recorded); err != nil {
+		return []string{fmt.Sprintf("recorded response is not valid JSON: %v", err)}
+	}
+	if err := json.Unmarshal(r.Response, &replayed); err != nil {
+		return []string{fmt.Sprintf("replayed response is not valid JSON: %v", err)}
+	}
+	withoutRequestID(recorded)
+	withoutRequestID(replayed)
+	var differences []string
+	diffJSON(recorded, replayed, "", &differences)
+	return differences
+}
+
+// withoutRequestID removes the echoed request ID from the decoded response, since generated IDs differ on every replay.
+func withoutRequestID(response any) {
+	decoded, ok := response.(map[string]any)
+	if !ok {
+		return
+	}
+	result, _ := decoded["result"].(map[string]any)
+	headers, ok := result["response_headers"].(map[string]any)
+	if !ok {
+		return
+	}
+	name := requestIDResponseHeader()
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			delete(headers, k)
+		}
+	}
+	if len(headers) == 0 {
+		delete(result, "response_headers")
+	}
+}
+
+// diffJSON appends the differences between the two decoded JSON values to out.
+func diffJSON(recorded, replayed any, path string, out *[]string) {
+	if recorded == redactedValue {
+		return
+	}
+
+	recordedObj, ok1 := recorded.(map[string]any)
+	replayedObj, ok2 := replayed.(map[string]any)
+	if ok1 && ok2 {
+		keys := make(map[string]bool)
+		for k := range recordedObj {
+			keys[k] = true
+		}
+		for k := range replayedObj {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			diffJSON(recordedObj[k], replayedObj[k], joinPath(path, k), out)
+		}
+		return
+	}
+
+	recordedArr, ok1 := recorded.([]any)
+	replayedArr, ok2 := replayed.([]any)
+	if ok1 && ok2 && len(recordedArr) == len(replayedArr) {
+		for i := range recordedArr {
+			diffJSON(recordedArr[i], replayedArr[i], joinPath(path, fmt.Sprint(i)), out)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(recorded, replayed) {
+		if path == "" {
+			path = "response"
+		}
+		*out = append(*out, fmt.Sprintf("%s: recorded %s, replayed %s", path, encodeForDiff(recorded), encodeForDiff(replayed)))
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func encodeForDiff(v any) string {
+	if v == nil {
+		return "nothing"
+	}
+	encoded, _ := json.Marshal(v)
+	return string(encoded)
+}
@@ -0,0 +1,88 @@
+package e5e_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	recordingFile := filepath.Join(t.TempDir(), "recording.jsonl")
+	t.Setenv("E5E_RECORD_FILE", recordingFile)
+	t.Setenv("E5E_RECORD_REDACT_PATHS", "context.data.Auth-Key")
+
+	e5e.AddHandlerFunc(t.Name(), func(ctx context.Context, r e5e.Request[IntegrationTestPayload, IntegrationTestContext]) (*e5e.Result, error) {
+		return &e5e.Result{Data: r.Data().A + r.Data().B}, nil
+	})
+
+	stdio := redirectStdio(t, "ping\n"+string(defaultPayload)+"\n")
+	os.Args = buildOptions(t.Name())
+	os.Args[3] = "1"
+	e5e.Start(context.Background())
+	stdio.ReadAndRestore()
+
+	recording, err := os.ReadFile(recordingFile)
+	if err != nil {
+		t.Fatalf("reading recording failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(recording)), "\n")
+	Equal(t, 1, len(lines), "pings must not be recorded")
+
+	var rec e5e.Recording
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatalf("decoding recording failed: %v", err)
+	}
+	Equal(t, t.Name(), rec.Entrypoint, "entrypoint does not match")
+	Equal(t, `{"result":{"data":5,"response_headers":{"X-Request-Id":"test-request-id"}}}`, string(rec.Response), "response does not match")
+
+	var request e5e.Request[IntegrationTestPayload, IntegrationTestContext]
+	if err := json.Unmarshal(rec.Request, &request); err != nil {
+		t.Fatalf("decoding recorded request failed: %v", err)
+	}
+	Equal(t, "[REDACTED]", request.Context.Data.AuthKey, "auth key was not redacted")
+	Equal(t, expectedRequest.Event.RequestHeaders["test-header"], request.Event.RequestHeaders["test-header"], "header does not match")
+
+	t.Run("replay matches", func(t *testing.T) {
+		results, err := e5e.Replay(context.Background(), bytes.NewReader(recording))
+		if err != nil {
+			t.Fatalf("replay failed: %v", err)
+		}
+		Equal(t, 1, len(results), "number of results does not match")
+		Equal(t, true, results[0].Equal(), "replayed response differs: "+strings.Join(results[0].Differences, ", "))
+	})
+	t.Run("replay reports differences", func(t *testing.T) {
+		rec := rec
+		rec.Response = json.RawMessage(`{"result":{"data":6,"status":200}}`)
+		tampered, _ := json.Marshal(rec)
+		unknown, _ := json.Marshal(e5e.Recording{Entrypoint: "does_not_exist", Request: rec.Request, Response: rec.Response})
+
+		results, err := e5e.Replay(context.Background(), bytes.NewReader(append(append(tampered, '\n'), unknown...)))
+		if err != nil {
+			t.Fatalf("replay failed: %v", err)
+		}
+		Equal(t, 2, len(results), "number of results does not match")
+		DeepEqual(t, []string{
+			"result.data: recorded 6, replayed 5",
+			"result.status: recorded 200, replayed nothing",
+		}, results[0].Differences, "differences do not match")
+		Equal(t, `entrypoint "does_not_exist" does not exist`, results[1].Err.Error(), "error does not match")
+		Equal(t, false, results[1].Equal(), "unknown entrypoint must differ")
+	})
+	t.Run("replay of a response that is not an object", func(t *testing.T) {
+		rec := rec
+		rec.Response = json.RawMessage(`null`)
+		edited, _ := json.Marshal(rec)
+
+		results, err := e5e.Replay(context.Background(), bytes.NewReader(edited))
+		if err != nil {
+			t.Fatalf("replay failed: %v", err)
+		}
+		Equal(t, false, results[0].Equal(), "null response must differ")
+	})
+}
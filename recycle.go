@@ -0,0 +1,131 @@
+package e5e
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables that control the recycling of daemons, see [OnShutdown].
+const (
+	envIdleTimeout    = "E5E_IDLE_TIMEOUT"
+	envMaxInvocations = "E5E_MAX_INVOCATIONS"
+	envMaxRSS         = "E5E_MAX_RSS"
+)
+
+// shutdownHooks are run when [Start] returns.
+var shutdownHooks struct {
+	mu    sync.Mutex
+	hooks []func(context.Context) error
+}
+
+// OnShutdown registers a hook that is run when [Start] returns, e.g. for flushing buffers or closing connections.
+// Hooks run in the reverse order they were registered, like deferred functions. Errors are logged.
+//
+// In keepalive mode, the daemon stops cleanly after the last complete execution, so it's replaced by a fresh process,
+// once one of the following limits, which are set by environment variables, is reached:
+//
+//   - E5E_IDLE_TIMEOUT: no event was received for the given duration, e.g. "10m".
+//   - E5E_MAX_INVOCATIONS: the given number of events was handled.
+//   - E5E_MAX_RSS: the resident memory of the process exceeds the given size, e.g. "256MiB".
+//
+// This prevents slow memory or resource leaks, e.g. in third-party libraries, from accumulating.
+func OnShutdown(hook func(ctx context.Context) error) {
+	shutdownHooks.mu.Lock()
+	defer shutdownHooks.mu.Unlock()
+	shutdownHooks.hooks = append(shutdownHooks.hooks, hook)
+}
+
+// runShutdownHooks runs all hooks registered with [OnShutdown]. They run even if the context was cancelled,
+// e.g. by a signal.
+func runShutdownHooks(ctx context.Context) {
+	shutdownHooks.mu.Lock()
+	hooks := shutdownHooks.hooks
+	shutdownHooks.hooks = nil
+	shutdownHooks.mu.Unlock()
+
+	ctx = context.WithoutCancel(ctx)
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](ctx); err != nil {
+			logger.Error("shutdown hook failed", "error", err.Error())
+		}
+	}
+}
+
+// recycling contains the limits after which a daemon in keepalive mode stops.
+type recycling struct {
+	idleTimeout    time.Duration
+	maxInvocations int
+	maxRSS         int64
+}
+
+// recyclingFromEnv reads the limits from the environment. Limits that are not set are 0.
+func recyclingFromEnv() (recycling, error) {
+	var r recycling
+	if v := os.Getenv(envIdleTimeout); v != "" {
+		var err error
+		if r.idleTimeout, err = time.ParseDuration(v); err != nil || r.idleTimeout <= 0 {
+			return r, fmt.Errorf("go-e5e: invalid %s %q", envIdleTimeout, v)
+		}
+	}
+	if v := os.Getenv(envMaxInvocations); v != "" {
+		var err error
+		if r.maxInvocations, err = strconv.Atoi(v); err != nil || r.maxInvocations <= 0 {
+			return r, fmt.Errorf("go-e5e: invalid %s %q", envMaxInvocations, v)
+		}
+	}
+	if v := os.Getenv(envMaxRSS); v != "" {
+		var err error
+		if r.maxRSS, err = parseByteSize(v); err != nil {
+			return r, fmt.Errorf("go-e5e: invalid %s %q", envMaxRSS, v)
+		}
+	}
+	return r, nil
+}
+
+// reason returns why the daemon should stop after the given number of invocations, or an empty string.
+func (r recycling) reason(invocations int) string {
+	if r.maxInvocations > 0 && invocations >= r.maxInvocations {
+		return "max_invocations"
+	}
+	if r.maxRSS > 0 && residentMemory() >= r.maxRSS {
+		return "max_rss"
+	}
+	return ""
+}
+
+// nextLine waits for the next line. It returns false if there are no more lines, or if the idle timeout passed first.
+// The idle timeout is disabled, if it's 0.
+func nextLine(lines <-chan []byte, idleTimeout time.Duration) ([]byte, bool) {
+	if idleTimeout == 0 {
+		line, ok := <-lines
+		return line, ok
+	}
+
+	timer := time.NewTimer(idleTimeout)
+	defer timer.Stop()
+	select {
+	case line, ok := <-lines:
+		return line, ok
+	case <-timer.C:
+		logger.Info("stopping the daemon for recycling", "reason", "idle_timeout")
+		return nil, false
+	}
+}
+
+// residentMemory returns the resident set size of the process. If it's not available,
+// the memory occupied by the Go runtime is returned instead.
+func residentMemory() int64 {
+	if b, err := os.ReadFile("/proc/self/statm"); err == nil {
+		if fields := strings.Fields(string(b)); len(fields) > 1 {
+			if pages, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				return pages * int64(os.Getpagesize())
+			}
+		}
+	}
+	return usedMemory()
+}
@@ -0,0 +1,106 @@
+package e5e_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestRecyclingAfterMaxInvocations(t *testing.T) {
+	t.Setenv("E5E_MAX_INVOCATIONS", "2")
+
+	var calls int
+	e5e.AddHandlerFunc(t.Name(), func(ctx context.Context, r e5e.Request[IntegrationTestPayload, IntegrationTestContext]) (*e5e.Result, error) {
+		calls++
+		return &e5e.Result{Data: calls}, nil
+	})
+	var shutdown []string
+	e5e.OnShutdown(func(ctx context.Context) error {
+		shutdown = append(shutdown, "first")
+		return nil
+	})
+	e5e.OnShutdown(func(ctx context.Context) error {
+		shutdown = append(shutdown, "second")
+		return errors.New("failed")
+	})
+
+	stdio := redirectStdio(t, "ping\n"+strings.Repeat(string(defaultPayload)+"\n", 3))
+	os.Args = buildOptions(t.Name())
+	os.Args[3] = "1"
+	e5e.Start(context.Background())
+	stdout, stderr := stdio.ReadAndRestore()
+
+	Equal(t, 2, calls, "number of calls does not match")
+	DeepEqual(t, []string{"second", "first"}, shutdown, "shutdown hooks did not run in reverse order")
+
+	frames := strings.Split(stdout, daemonTerminationSequence)
+	Equal(t, 4, len(frames), "number of frames does not match")
+	Equal(t, "", frames[3], "output after the last frame")
+
+	logs := strings.Split(stderr, daemonTerminationSequence)
+	Equal(t, 4, len(logs), "number of stderr frames does not match")
+	Equal(t, true, strings.Contains(logs[3], `"reason":"max_invocations"`), "recycling was not logged: "+logs[3])
+	Equal(t, true, strings.Contains(logs[3], `"msg":"shutdown hook failed","error":"failed"`), "hook error was not logged: "+logs[3])
+}
+
+// TestRecyclingAfterIdleTimeout runs in a separate process, since stdin must stay open.
+func TestRecyclingAfterIdleTimeout(t *testing.T) {
+	if os.Getenv("E5E_TEST_IDLE_TIMEOUT") == "1" {
+		e5e.AddHandlerFunc(t.Name(), func(ctx context.Context, r e5e.Request[any, any]) (*e5e.Result, error) {
+			return &e5e.Result{Data: "done"}, nil
+		})
+		e5e.OnShutdown(func(ctx context.Context) error {
+			e5e.Logger(ctx).Info("shut down")
+			return nil
+		})
+		os.Args = buildOptions(t.Name())
+		os.Args[3] = "1"
+		e5e.Start(context.Background())
+		os.Exit(0)
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		t.Fatalf("locating the test binary failed: %v", err)
+	}
+	cmd := exec.Command(binary, "-test.run=^TestRecyclingAfterIdleTimeout$")
+	cmd.Env = append(os.Environ(), "E5E_TEST_IDLE_TIMEOUT=1", "E5E_IDLE_TIMEOUT=100ms")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("creating stdin pipe failed: %v", err)
+	}
+	defer stdin.Close()
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("starting the helper process failed: %v", err)
+	}
+	_, _ = stdin.Write(append(defaultPayload, '\n'))
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("running the helper process failed: %v\n%s", err, stderr.String())
+		}
+	case <-time.After(10 * time.Second):
+		_ = cmd.Process.Kill()
+		t.Fatal("the daemon did not stop after the idle timeout")
+	}
+
+	frames := strings.Split(stdout.String(), daemonTerminationSequence)
+	Equal(t, 2, len(frames), "number of frames does not match")
+	Equal(t, true, strings.Contains(frames[0], `"data":"done"`), "result is missing")
+
+	logs := strings.Split(stderr.String(), daemonTerminationSequence)
+	Equal(t, true, strings.Contains(logs[1], `"reason":"idle_timeout"`), "recycling was not logged: "+logs[1])
+	Equal(t, true, strings.Contains(logs[1], `"msg":"shut down"`), "shutdown hook did not run: "+logs[1])
+}
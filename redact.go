@@ -0,0 +1,182 @@
+package e5e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// redactedValue replaces every redacted value.
+const redactedValue = "[REDACTED]"
+
+// redactor removes sensitive values from request envelopes and responses before they leave the process,
+// e.g. when they are written to a recording.
+type redactor struct {
+	// Names of request and response headers whose values are redacted, compared case-insensitively.
+	headers []string
+
+	// Keys of the context data whose values are redacted, compared case-insensitively.
+	contextData []string
+
+	// Dot-separated paths of JSON values that are redacted, e.g. "event.data.password".
+	// A "*" matches every key of an object or every element of an array.
+	paths [][]string
+
+	// If set, the base64 encoded contents of files are truncated to a short summary.
+	truncateFiles bool
+}
+
+// defaultRedactedHeaders are redacted, if no headers are configured explicitly.
+var defaultRedactedHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "Set-Cookie"}
+
+// truncatedFilePrefix is the number of base64 characters of a file that are kept when files are truncated.
+const truncatedFilePrefix = 32
+
+// redactorFromEnv creates a redactor from comma-separated lists in the given environment variables.
+// If the headers variable is not set, [defaultRedactedHeaders] are used.
+func redactorFromEnv(headersKey, contextDataKey, pathsKey string) redactor {
+	headers := defaultRedactedHeaders
+	if v, ok := os.LookupEnv(headersKey); ok {
+		headers = splitList(v)
+	}
+	return newRedactor(headers, splitList(os.Getenv(contextDataKey)), splitList(os.Getenv(pathsKey)))
+}
+
+func newRedactor(headers, contextData, paths []string) redactor {
+	r := redactor{headers: headers, contextData: contextData}
+	for _, p := range paths {
+		r.paths = append(r.paths, strings.Split(p, "."))
+	}
+	return r
+}
+
+// splitList splits a comma-separated list and removes empty elements.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// redact returns a copy of the given JSON document with all configured values replaced.
+// If the document is not valid JSON, it is returned as is.
+func (r redactor) redact(document []byte) []byte {
+	if len(r.headers) == 0 && len(r.contextData) == 0 && len(r.paths) == 0 && !r.truncateFiles {
+		return document
+	}
+
+	var v any
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return document
+	}
+
+	r.redactValue(v)
+	redacted, err := json.Marshal(v)
+	if err != nil {
+		return document
+	}
+	return redacted
+}
+
+// redactValue modifies the decoded JSON value in place.
+func (r redactor) redactValue(v any) {
+	for _, headersPath := range [][]string{{"event", "request_headers"}, {"result", "response_headers"}} {
+		headers, ok := lookupPath(v, headersPath).(map[string]any)
+		if !ok {
+			continue
+		}
+		for k := range headers {
+			for _, name := range r.headers {
+				if strings.EqualFold(k, name) {
+					headers[k] = redactedValue
+				}
+			}
+		}
+	}
+	if contextData, ok := lookupPath(v, []string{"context", "data"}).(map[string]any); ok {
+		for k := range contextData {
+			for _, name := range r.contextData {
+				if strings.EqualFold(k, name) {
+					contextData[k] = redactedValue
+				}
+			}
+		}
+	}
+	for _, p := range r.paths {
+		redactPath(v, p)
+	}
+	if r.truncateFiles {
+		truncateFiles(v)
+	}
+}
+
+// truncateFiles shortens the base64 encoded contents of all files inside the decoded JSON value
+// and notes how many characters were removed.
+func truncateFiles(v any) {
+	switch node := v.(type) {
+	case map[string]any:
+		if encoded, ok := node["binary"].(string); ok && node["type"] == "binary" && len(encoded) > truncatedFilePrefix {
+			node["binary"] = fmt.Sprintf("%s...[%d more characters]", encoded[:truncatedFilePrefix], len(encoded)-truncatedFilePrefix)
+			return
+		}
+		for _, child := range node {
+			truncateFiles(child)
+		}
+	case []any:
+		for _, child := range node {
+			truncateFiles(child)
+		}
+	}
+}
+
+// lookupPath returns the value at the given path inside objects, or nil if it does not exist.
+func lookupPath(v any, path []string) any {
+	for _, key := range path {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = obj[key]
+	}
+	return v
+}
+
+// redactPath replaces all values matching the given path.
+func redactPath(v any, path []string) {
+	if len(path) == 0 {
+		return
+	}
+	key, rest := path[0], path[1:]
+
+	switch node := v.(type) {
+	case map[string]any:
+		for k, child := range node {
+			if key != "*" && k != key {
+				continue
+			}
+			if len(rest) == 0 {
+				node[k] = redactedValue
+			} else {
+				redactPath(child, rest)
+			}
+		}
+	case []any:
+		if key != "*" {
+			return
+		}
+		for i, child := range node {
+			if len(rest) == 0 {
+				node[i] = redactedValue
+			} else {
+				redactPath(child, rest)
+			}
+		}
+	}
+}
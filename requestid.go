@@ -0,0 +1,122 @@
+package e5e
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envRequestIDHeaders is the environment variable that sets the request headers the request ID is read from,
+// as comma-separated list.
+const envRequestIDHeaders = "E5E_REQUEST_ID_HEADERS"
+
+// defaultRequestIDHeaders are the request headers the request ID is read from, if none are configured.
+var defaultRequestIDHeaders = []string{"X-Request-Id", "X-Correlation-Id", "traceparent"}
+
+// requestIDHeaders are the request headers the request ID is read from, in order of precedence.
+var requestIDHeaders = defaultRequestIDHeaders
+
+// RequestID returns the ID of the request the context belongs to, or an empty string outside of an invocation.
+//
+// The ID is read from the first of the request headers X-Request-Id, X-Correlation-Id and traceparent that was sent.
+// For the traceparent header, the trace ID is used. The headers can be changed with the environment variable
+// E5E_REQUEST_ID_HEADERS, e.g. "X-Amzn-Trace-Id,X-Request-Id". If none of the headers was sent, a random ID is generated.
+//
+// The ID is part of every line written by [Logger] and of errors returned by the handler, which are reported
+// to E5E. It's echoed in the response header with the name of the first configured header,
+// unless the handler already set it.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// requestIDContextKey is the context key of the request ID of an invocation.
+type requestIDContextKey struct{}
+
+// requestError wraps the error returned by the handler of an invocation with the request ID.
+type requestError struct {
+	requestID string
+	err       error
+}
+
+func (e *requestError) Error() string { return fmt.Sprintf("request %s: %v", e.requestID, e.err) }
+func (e *requestError) Unwrap() error { return e.err }
+
+// handlerErrorMessage returns the message of the error returned by the handler, without the request ID,
+// which differs between executions if it was generated.
+func handlerErrorMessage(err error) string {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		return reqErr.err.Error()
+	}
+	return err.Error()
+}
+
+// configureRequestID sets the request headers the request ID is read from, from the environment.
+func configureRequestID() error {
+	v, ok := os.LookupEnv(envRequestIDHeaders)
+	if !ok {
+		requestIDHeaders = defaultRequestIDHeaders
+		return nil
+	}
+	var headers []string
+	for _, name := range strings.Split(v, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			headers = append(headers, name)
+		}
+	}
+	if len(headers) == 0 {
+		return fmt.Errorf("go-e5e: invalid %s %q", envRequestIDHeaders, v)
+	}
+	requestIDHeaders = headers
+	return nil
+}
+
+// requestIDFromHeaders returns the request ID sent in the request headers, or a random one.
+func requestIDFromHeaders(headers map[string]string) string {
+	for _, name := range requestIDHeaders {
+		v := strings.TrimSpace(headerValue(headers, name))
+		if v == "" {
+			continue
+		}
+		if strings.EqualFold(name, "traceparent") {
+			sc, ok := parseTraceParent(v)
+			if !ok {
+				continue
+			}
+			return sc.TraceID.String()
+		}
+		return v
+	}
+	return newRequestID()
+}
+
+// newRequestID returns a random request ID.
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestIDResponseHeader returns the name of the response header the request ID is echoed in.
+func requestIDResponseHeader() string {
+	if name := requestIDHeaders[0]; !strings.EqualFold(name, "traceparent") {
+		return name
+	}
+	return "X-Request-Id"
+}
+
+// echoRequestID returns the result with the request ID set as response header, unless the handler already set it.
+func echoRequestID(res *Result, id string) *Result {
+	name := requestIDResponseHeader()
+	if res == nil || headerValue(res.ResponseHeaders, name) != "" {
+		return res
+	}
+	echoed := *res
+	echoed.ResponseHeaders = setHeader(cloneHeaders(res.ResponseHeaders), name, id)
+	return &echoed
+}
@@ -0,0 +1,130 @@
+package e5e_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestRequestID(t *testing.T) {
+	generated := regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+	tests := []struct {
+		name            string
+		env             string
+		requestHeaders  map[string]string
+		responseHeaders map[string]string
+		requestID       string
+		echoHeader      string
+	}{
+		{
+			name:           "request id header",
+			requestHeaders: map[string]string{"x-request-id": "abc", "X-Correlation-Id": "def"},
+			requestID:      "abc",
+			echoHeader:     "X-Request-Id",
+		},
+		{
+			name:           "correlation id header",
+			requestHeaders: map[string]string{"X-Correlation-Id": "def"},
+			requestID:      "def",
+			echoHeader:     "X-Request-Id",
+		},
+		{
+			name:           "traceparent",
+			requestHeaders: map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+			requestID:      "0af7651916cd43dd8448eb211c80319c",
+			echoHeader:     "X-Request-Id",
+		},
+		{
+			name:       "generated",
+			echoHeader: "X-Request-Id",
+		},
+		{
+			name:            "set by the handler",
+			requestHeaders:  map[string]string{"X-Request-Id": "abc"},
+			responseHeaders: map[string]string{"x-request-id": "custom"},
+			requestID:       "abc",
+			echoHeader:      "x-request-id",
+		},
+		{
+			name:           "configured headers",
+			env:            "X-Amzn-Trace-Id, X-Request-Id",
+			requestHeaders: map[string]string{"X-Request-Id": "abc", "X-Amzn-Trace-Id": "Root=1-abc"},
+			requestID:      "Root=1-abc",
+			echoHeader:     "X-Amzn-Trace-Id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv("E5E_REQUEST_ID_HEADERS", tt.env)
+			}
+
+			var requestID string
+			e5e.AddHandlerFunc(t.Name(), func(ctx context.Context, r e5e.Request[IntegrationTestPayload, any]) (*e5e.Result, error) {
+				requestID = e5e.RequestID(ctx)
+				e5e.Logger(ctx).Info("summing")
+				return &e5e.Result{ResponseHeaders: tt.responseHeaders, Data: r.Data().A + r.Data().B}, nil
+			})
+
+			payload, _ := json.Marshal(map[string]any{
+				"event":   map[string]any{"type": "object", "data": map[string]int{"a": 2, "b": 3}, "request_headers": tt.requestHeaders},
+				"context": map[string]any{"type": "http"},
+			})
+			stdout, stderr := invokeE5E(t, string(payload))
+
+			if tt.requestID == "" {
+				Equal(t, true, generated.MatchString(requestID), "request id was not generated: "+requestID)
+			} else {
+				Equal(t, tt.requestID, requestID, "request id does not match")
+			}
+
+			var res struct {
+				Result e5e.Result `json:"result"`
+			}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(stdout, stdoutTerminationSequence)), &res); err != nil {
+				t.Fatalf("decoding result failed: %v", err)
+			}
+			expectedHeader := requestID
+			if v, ok := tt.responseHeaders[tt.echoHeader]; ok {
+				expectedHeader = v
+			}
+			Equal(t, expectedHeader, res.Result.ResponseHeaders[tt.echoHeader], "echoed request id does not match")
+			Equal(t, 1, len(res.Result.ResponseHeaders), "request id was echoed twice")
+
+			var line map[string]any
+			if err := json.Unmarshal([]byte(stderr), &line); err != nil {
+				t.Fatalf("log line %q is not valid JSON: %v", stderr, err)
+			}
+			Equal[any](t, requestID, line["request_id"], "logged request id does not match")
+		})
+	}
+}
+
+func TestRequestIDOfErrors(t *testing.T) {
+	e5e.AddHandlerFunc(t.Name(), func(ctx context.Context, r e5e.Request[IntegrationTestPayload, any]) (*e5e.Result, error) {
+		return nil, errors.New("something failed")
+	})
+
+	stdio := redirectStdio(t, string(defaultPayload))
+	os.Args = buildOptions(t.Name())
+	func() {
+		defer func() { _ = recover() }()
+		e5e.Start(context.Background())
+	}()
+	_, stderr := stdio.ReadAndRestore()
+
+	var line map[string]any
+	if err := json.Unmarshal([]byte(stderr), &line); err != nil {
+		t.Fatalf("log line %q is not valid JSON: %v", stderr, err)
+	}
+	Equal[any](t, "ERROR", line["level"], "level does not match")
+	Equal[any](t, "something failed", line["error"], "error does not match")
+	Equal[any](t, "test-request-id", line["request_id"], "request id does not match")
+}
@@ -0,0 +1,75 @@
+package e5e
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// RouteSource describes where the HTTP method and the path of an event are read from.
+// E5E itself does not forward them, so they are usually set by a gateway in front of the function.
+//
+// Headers take precedence over params. Empty names are ignored.
+type RouteSource struct {
+	// The name of the request header that contains the HTTP method.
+	MethodHeader string
+
+	// The name of the GET parameter that contains the HTTP method.
+	MethodParam string
+
+	// The name of the request header that contains the path.
+	PathHeader string
+
+	// The name of the GET parameter that contains the path.
+	PathParam string
+}
+
+// DefaultRouteSource reads the method from the "X-HTTP-Method" header and the path
+// from the "X-HTTP-Path" header or the "path" GET parameter.
+var DefaultRouteSource = RouteSource{
+	MethodHeader: "X-HTTP-Method",
+	PathHeader:   "X-HTTP-Path",
+	PathParam:    "path",
+}
+
+// route returns the HTTP method and the path of the event.
+// If no method is given, it's GET for events without data and POST otherwise.
+// The returned path always starts with a slash and may contain a query string.
+func (s RouteSource) route(e Event[json.RawMessage]) (method, p string) {
+	method = s.lookup(e, s.MethodHeader, s.MethodParam)
+	if method == "" {
+		method = http.MethodGet
+		if e.Type != "" && len(e.Data) > 0 && string(e.Data) != "null" {
+			method = http.MethodPost
+		}
+	}
+
+	p = s.lookup(e, s.PathHeader, s.PathParam)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return strings.ToUpper(method), p
+}
+
+// params returns the params of the event without the ones the route was read from.
+func (s RouteSource) params(e Event[json.RawMessage]) map[string][]string {
+	params := make(map[string][]string, len(e.Params))
+	for k, v := range e.Params {
+		if k != s.MethodParam && k != s.PathParam {
+			params[k] = v
+		}
+	}
+	return params
+}
+
+func (s RouteSource) lookup(e Event[json.RawMessage], header, param string) string {
+	if header != "" {
+		if v := e.Header(header); v != "" {
+			return v
+		}
+	}
+	if param != "" && len(e.Params[param]) > 0 {
+		return e.Params[param][0]
+	}
+	return ""
+}
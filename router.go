@@ -0,0 +1,244 @@
+package e5e
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Router is a [HandlerFactory] that dispatches the events of one entrypoint to different handlers,
+// based on the HTTP method and the path of the event. Use [NewRouter] to create one.
+//
+// Patterns consist of literal segments, named parameters like "{id}" and an optional trailing
+// wildcard like "{path...}" that matches the rest of the path. Routes are matched in the order they were added.
+// The values of the parameters are available using [PathParam].
+//
+// If no route matches the path, a result with status 404 is returned. If routes match the path,
+// but not the method, a result with status 405 and an Allow header listing the allowed methods is returned.
+//
+//	router := e5e.NewRouter()
+//	e5e.Route(router, http.MethodGet, "/users/{id}", getUser)
+//	admin := router.Group("/admin", requireAdmin)
+//	e5e.Route(admin, http.MethodDelete, "/users/{id}", deleteUser)
+//	e5e.AddHandler("API", router)
+type Router struct {
+	// Where the method and the path of the events are read from. Only the one of the root router is used.
+	RouteSource
+
+	parent      *Router
+	prefix      string
+	middlewares []Middleware
+	routes      *[]*route
+}
+
+// route is a single route of a [Router].
+type route struct {
+	method   string
+	segments []string
+	group    *Router
+	factory  HandlerFactory
+}
+
+// NewRouter returns a router without any routes, reading the method and the path as described by [DefaultRouteSource].
+func NewRouter() *Router {
+	return &Router{RouteSource: DefaultRouteSource, routes: new([]*route)}
+}
+
+// Handle adds a route for the given method and path pattern. An empty method matches all methods.
+// The pattern is relative to the prefix of the group. It panics if the pattern is invalid.
+func (r *Router) Handle(method, pattern string, factory HandlerFactory) {
+	segments, err := parsePattern(r.fullPrefix() + "/" + strings.TrimPrefix(pattern, "/"))
+	if err != nil {
+		panic(fmt.Errorf("go-e5e: invalid route pattern %q: %w", pattern, err))
+	}
+	*r.routes = append(*r.routes, &route{
+		method:   strings.ToUpper(method),
+		segments: segments,
+		group:    r,
+		factory:  factory,
+	})
+}
+
+// Route adds a route with a typed handler function to the router, see [Router.Handle].
+func Route[T, TContext Data](r *Router, method, pattern string, fn func(context.Context, Request[T, TContext]) (*Result, error)) {
+	r.Handle(method, pattern, NewHandlerFactory[T, TContext](HandlerFunc[T, TContext](fn)))
+}
+
+// Use adds middleware to the router, which is applied to all routes of the router and its groups.
+// Middleware of a router is applied outside of the middleware of its groups.
+func (r *Router) Use(middleware ...Middleware) {
+	r.middlewares = append(r.middlewares, middleware...)
+}
+
+// Group returns a router whose routes are added below the given path prefix to this router.
+// The given middleware is only applied to the routes of the group.
+func (r *Router) Group(prefix string, middleware ...Middleware) *Router {
+	group := &Router{
+		RouteSource: r.RouteSource,
+		parent:      r,
+		prefix:      "/" + strings.Trim(prefix, "/"),
+		routes:      r.routes,
+	}
+	group.Use(middleware...)
+	return group
+}
+
+func (r *Router) fullPrefix() string {
+	if r.parent == nil {
+		return strings.TrimSuffix(r.prefix, "/")
+	}
+	return r.parent.fullPrefix() + strings.TrimSuffix(r.prefix, "/")
+}
+
+// Execute implements [HandlerFactory].
+func (r *Router) Execute(ctx context.Context, payload []byte) (*Result, error) {
+	request, err := decodeRawRequest(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	root := r
+	for root.parent != nil {
+		root = root.parent
+	}
+	method, p := root.route(request.Event)
+	p, _, _ = strings.Cut(p, "?")
+	segments := strings.Split(strings.TrimPrefix(path.Clean("/"+p), "/"), "/")
+
+	var allowed []string
+	for _, rt := range *r.routes {
+		params, ok := rt.match(segments)
+		if !ok {
+			continue
+		}
+		if rt.method != "" && rt.method != method {
+			allowed = append(allowed, rt.method)
+			continue
+		}
+		ctx = context.WithValue(ctx, pathParamsContextKey{}, params)
+		return rt.handler().Execute(ctx, payload)
+	}
+
+	if len(allowed) > 0 {
+		sort.Strings(allowed)
+		return &Result{
+			Status:          http.StatusMethodNotAllowed,
+			ResponseHeaders: map[string]string{"Allow": strings.Join(dedupe(allowed), ", ")},
+			Type:            ResultDataTypeText,
+			Data:            "method not allowed",
+		}, nil
+	}
+	return &Result{Status: http.StatusNotFound, Type: ResultDataTypeText, Data: "not found"}, nil
+}
+
+// handler returns the handler of the route, wrapped with the middleware of its group and all parent groups.
+func (rt *route) handler() HandlerFactory {
+	factory := rt.factory
+	for g := rt.group; g != nil; g = g.parent {
+		for i := len(g.middlewares) - 1; i >= 0; i-- {
+			factory = g.middlewares[i](factory)
+		}
+	}
+	return factory
+}
+
+// match returns the path parameters, if the route matches the given path segments.
+func (rt *route) match(segments []string) (map[string]string, bool) {
+	params := make(map[string]string)
+	for i, s := range rt.segments {
+		if name, ok := wildcardName(s); ok {
+			params[name] = strings.Join(segments[i:], "/")
+			return params, true
+		}
+		if i >= len(segments) {
+			return nil, false
+		}
+		if name, ok := paramName(s); ok {
+			if segments[i] == "" {
+				return nil, false
+			}
+			value, err := url.PathUnescape(segments[i])
+			if err != nil {
+				return nil, false
+			}
+			params[name] = value
+			continue
+		}
+		if s != segments[i] {
+			return nil, false
+		}
+	}
+	return params, len(segments) == len(rt.segments)
+}
+
+// parsePattern splits a route pattern into its segments and validates them.
+func parsePattern(pattern string) ([]string, error) {
+	segments := strings.Split(strings.TrimPrefix(path.Clean("/"+pattern), "/"), "/")
+	names := make(map[string]bool)
+	for i, s := range segments {
+		name, isWildcard := wildcardName(s)
+		if isWildcard && i != len(segments)-1 {
+			return nil, fmt.Errorf("wildcard %q must be the last segment", s)
+		}
+		if !isWildcard {
+			var isParam bool
+			if name, isParam = paramName(s); !isParam {
+				if strings.ContainsAny(s, "{}") {
+					return nil, fmt.Errorf("invalid segment %q", s)
+				}
+				continue
+			}
+		}
+		if name == "" || names[name] {
+			return nil, fmt.Errorf("invalid or duplicate parameter %q", s)
+		}
+		names[name] = true
+	}
+	return segments, nil
+}
+
+func paramName(segment string) (string, bool) {
+	if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") || strings.HasSuffix(segment, "...}") {
+		return "", false
+	}
+	return segment[1 : len(segment)-1], true
+}
+
+func wildcardName(segment string) (string, bool) {
+	if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "...}") {
+		return "", false
+	}
+	return segment[1 : len(segment)-4], true
+}
+
+func dedupe(sorted []string) []string {
+	out := sorted[:0]
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// pathParamsContextKey is the context key of the path parameters matched by a [Router].
+type pathParamsContextKey struct{}
+
+// PathParam returns the value of the path parameter with the given name, matched by a [Router].
+// It returns an empty string, if there is no such parameter.
+func PathParam(ctx context.Context, name string) string {
+	return PathParams(ctx)[name]
+}
+
+// PathParams returns all path parameters matched by a [Router].
+func PathParams(ctx context.Context) map[string]string {
+	params, _ := ctx.Value(pathParamsContextKey{}).(map[string]string)
+	return params
+}
+
+// compile-time check for certain interfaces
+var _ HandlerFactory = &Router{}
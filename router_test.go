@@ -0,0 +1,113 @@
+package e5e_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestRouter(t *testing.T) {
+	t.Parallel()
+
+	type user struct {
+		Name string `json:"name"`
+	}
+	router := e5e.NewRouter()
+	router.Use(func(next e5e.HandlerFactory) e5e.HandlerFactory {
+		return e5e.HandlerFactoryFunc(func(ctx context.Context, payload []byte) (*e5e.Result, error) {
+			res, err := next.Execute(ctx, payload)
+			if res != nil {
+				res.ResponseHeaders = map[string]string{"X-Router": "1"}
+			}
+			return res, err
+		})
+	})
+	e5e.Route(router, http.MethodGet, "/users/{id}", func(ctx context.Context, r e5e.Request[any, any]) (*e5e.Result, error) {
+		return &e5e.Result{Data: "get " + e5e.PathParam(ctx, "id")}, nil
+	})
+	e5e.Route(router, http.MethodPut, "/users/{id}", func(ctx context.Context, r e5e.Request[user, any]) (*e5e.Result, error) {
+		return &e5e.Result{Data: "put " + e5e.PathParam(ctx, "id") + " " + r.Data().Name}, nil
+	})
+	e5e.Route(router, "", "/files/{path...}", func(ctx context.Context, r e5e.Request[any, any]) (*e5e.Result, error) {
+		return &e5e.Result{Data: "file " + e5e.PathParam(ctx, "path")}, nil
+	})
+
+	admin := router.Group("/admin", func(next e5e.HandlerFactory) e5e.HandlerFactory {
+		return e5e.HandlerFactoryFunc(func(ctx context.Context, payload []byte) (*e5e.Result, error) {
+			var r e5e.Request[any, any]
+			_ = json.Unmarshal(payload, &r)
+			if r.Event.Header("X-Admin") != "yes" {
+				return &e5e.Result{Status: http.StatusForbidden}, nil
+			}
+			return next.Execute(ctx, payload)
+		})
+	})
+	e5e.Route(admin, http.MethodDelete, "/users/{id}", func(ctx context.Context, r e5e.Request[any, any]) (*e5e.Result, error) {
+		return &e5e.Result{Data: "delete " + e5e.PathParam(ctx, "id")}, nil
+	})
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		data    string
+		headers map[string]string
+		status  int
+		result  any
+		allow   string
+	}{
+		{name: "path param", method: "GET", path: "/users/42", result: "get 42"},
+		{name: "escaped path param", method: "GET", path: "/users/a%2Fb?x=1", result: "get a/b"},
+		{name: "typed data", method: "PUT", path: "/users/42", data: `{"name":"jane"}`, result: "put 42 jane"},
+		{name: "wildcard", method: "POST", path: "/files/a/b.txt", result: "file a/b.txt"},
+		{name: "method not allowed", method: "POST", path: "/users/42", status: 405, result: "method not allowed", allow: "GET, PUT"},
+		{name: "not found", method: "GET", path: "/users", status: 404, result: "not found"},
+		{name: "group", method: "DELETE", path: "/admin/users/1", headers: map[string]string{"X-Admin": "yes"}, result: "delete 1"},
+		{name: "group middleware", method: "DELETE", path: "/admin/users/1", status: 403},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			headers := map[string]string{"X-HTTP-Method": tt.method}
+			for k, v := range tt.headers {
+				headers[k] = v
+			}
+			event := map[string]any{"request_headers": headers, "params": map[string][]string{"path": {tt.path}}}
+			if tt.data != "" {
+				event["type"] = "object"
+				event["data"] = json.RawMessage(tt.data)
+			}
+			payload, _ := json.Marshal(map[string]any{"event": event})
+
+			res, err := router.Execute(context.Background(), payload)
+			if err != nil {
+				t.Fatalf("routing failed: %v", err)
+			}
+			Equal(t, tt.status, res.Status, "status does not match")
+			DeepEqual(t, tt.result, res.Data, "data does not match")
+			Equal(t, tt.allow, res.ResponseHeaders["Allow"], "allow header does not match")
+			if tt.status == 0 {
+				Equal(t, "1", res.ResponseHeaders["X-Router"], "router middleware was not applied")
+			}
+		})
+	}
+}
+
+func TestRouterInvalidPattern(t *testing.T) {
+	t.Parallel()
+
+	for _, pattern := range []string{"/{path...}/x", "/{id}/{id}", "/{}", "/a{b"} {
+		pattern := pattern
+		t.Run(pattern, func(t *testing.T) {
+			t.Parallel()
+			defer func() {
+				Equal(t, true, recover() != nil, "invalid pattern must panic")
+			}()
+			e5e.NewRouter().Handle("GET", pattern, nil)
+		})
+	}
+}
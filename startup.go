@@ -0,0 +1,133 @@
+package e5e
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// startup records the timing of the cold start of the process.
+var startup = &startupTiming{processStart: time.Now()}
+
+// startupTiming contains the points in time of the cold start. The process start is approximated
+// by the time this package was initialized, which happens before the main function runs.
+type startupTiming struct {
+	mu           sync.Mutex
+	processStart time.Time
+	startCalled  time.Time
+	initHooks    time.Duration
+	firstEvent   time.Time
+	hooks        []func(context.Context) error
+}
+
+// coldStartContextKey is the context key that marks the first invocation of the process.
+type coldStartContextKey struct{}
+
+// OnInit registers a hook that is run by [Start] before the first event is received,
+// e.g. for connecting to a database. Hooks run in the order they were registered.
+// If a hook returns an error, [Start] panics.
+//
+// The time spent in the hooks is reported as part of the cold start, see [IsColdStart].
+func OnInit(hook func(ctx context.Context) error) {
+	startup.mu.Lock()
+	defer startup.mu.Unlock()
+	startup.hooks = append(startup.hooks, hook)
+}
+
+// IsColdStart returns true if the context belongs to the first invocation of the process,
+// so handlers can skip expensive optional work, like warming caches, while the caller is waiting.
+//
+// The timing of the cold start is reported in the metadata, the metrics (see [WriteMetrics])
+// and in a "cold start" log line of the first invocation (see [Logger]). All log lines and the span
+// of the invocation have a "cold_start" attribute. The following phases are timed:
+//
+//   - The time from the process start until [Start] was called.
+//   - The time spent in the hooks registered with [OnInit].
+//   - The time from the process start until the first event was received.
+func IsColdStart(ctx context.Context) bool {
+	cold, _ := ctx.Value(coldStartContextKey{}).(bool)
+	return cold
+}
+
+// markStartCalled records the time [Start] was called.
+func (s *startupTiming) markStartCalled() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.startCalled.IsZero() {
+		s.startCalled = time.Now()
+	}
+}
+
+// runInitHooks runs all hooks registered with [OnInit] and records the time spent.
+func (s *startupTiming) runInitHooks(ctx context.Context) error {
+	s.mu.Lock()
+	hooks := s.hooks
+	s.hooks = nil
+	s.mu.Unlock()
+
+	start := time.Now()
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.initHooks += time.Since(start)
+	}()
+	for i, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			return fmt.Errorf("go-e5e: init hook %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// hookCount returns the number of hooks registered with [OnInit] that have not run yet.
+func (s *startupTiming) hookCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.hooks)
+}
+
+// markEvent records the time an event was received and returns true, if it's the first one.
+func (s *startupTiming) markEvent() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.firstEvent.IsZero() {
+		return false
+	}
+	s.firstEvent = time.Now()
+	return true
+}
+
+// durations returns the duration of each phase of the cold start that has been reached already,
+// in the order the phases happen.
+func (s *startupTiming) durations() []startupPhase {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var phases []startupPhase
+	if !s.startCalled.IsZero() {
+		phases = append(phases,
+			startupPhase{"until_start", s.startCalled.Sub(s.processStart)},
+			startupPhase{"init_hooks", s.initHooks},
+		)
+	}
+	if !s.firstEvent.IsZero() {
+		phases = append(phases, startupPhase{"first_event", s.firstEvent.Sub(s.processStart)})
+	}
+	return phases
+}
+
+// log writes the duration of each phase of the cold start that has been reached already as a single line.
+func (s *startupTiming) log(l *slog.Logger) {
+	var attrs []any
+	for _, phase := range s.durations() {
+		attrs = append(attrs, slog.Float64(phase.name+"_seconds", phase.duration.Seconds()))
+	}
+	l.Info("cold start", attrs...)
+}
+
+// startupPhase is the duration of a single phase of the cold start.
+type startupPhase struct {
+	name     string
+	duration time.Duration
+}
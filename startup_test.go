@@ -0,0 +1,86 @@
+package e5e_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"go.anx.io/e5e/v2"
+)
+
+// TestColdStart runs in a separate process, since only the first event of a process is a cold start.
+func TestColdStart(t *testing.T) {
+	if os.Getenv("E5E_TEST_COLD_START") == "1" {
+		var initialized bool
+		e5e.OnInit(func(ctx context.Context) error {
+			time.Sleep(10 * time.Millisecond)
+			initialized = true
+			return nil
+		})
+		e5e.AddHandlerFunc(t.Name(), func(ctx context.Context, r e5e.Request[any, any]) (*e5e.Result, error) {
+			if !initialized {
+				return nil, errors.New("init hook did not run")
+			}
+			e5e.Logger(ctx).Info("handling")
+			return &e5e.Result{Data: e5e.IsColdStart(ctx)}, nil
+		})
+		os.Args = buildOptions(t.Name())
+		os.Args[3] = "1"
+		e5e.Start(context.Background())
+		os.Exit(0)
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		t.Fatalf("locating the test binary failed: %v", err)
+	}
+	cmd := exec.Command(binary, "-test.run=^TestColdStart$")
+	cmd.Env = append(os.Environ(), "E5E_TEST_COLD_START=1")
+	cmd.Stdin = strings.NewReader(string(defaultPayload) + "\n" + string(defaultPayload) + "\nmetrics\n")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("running the helper process failed: %v\n%s", err, stderr.String())
+	}
+
+	frames := strings.Split(stdout.String(), daemonTerminationSequence)
+	Equal(t, 4, len(frames), "number of frames does not match")
+	Equal(t, `{"result":{"response_headers":{"X-Request-Id":"test-request-id"},"data":true}}`,
+		strings.TrimPrefix(frames[0], stdoutTerminationSequence), "first event must be a cold start")
+	Equal(t, `{"result":{"response_headers":{"X-Request-Id":"test-request-id"},"data":false}}`,
+		strings.TrimPrefix(frames[1], stdoutTerminationSequence), "second event must not be a cold start")
+
+	exposition := frames[2]
+	for _, phase := range []string{"until_start", "init_hooks", "first_event"} {
+		Equal(t, true, strings.Contains(exposition, `e5e_startup_duration_seconds{phase="`+phase+`"}`), "metrics do not contain "+phase)
+	}
+	Equal(t, false, strings.Contains(exposition, `e5e_startup_duration_seconds{phase="init_hooks"} 0`+"\n"), "init hooks were not timed")
+
+	logs := strings.Split(stderr.String(), daemonTerminationSequence)
+	for i, expected := range []bool{true, false} {
+		lines := strings.Split(strings.TrimSuffix(logs[i], "\n"), "\n")
+		Equal(t, 2-i, len(lines), "number of log lines does not match")
+		var line map[string]any
+		if err := json.Unmarshal([]byte(lines[len(lines)-1]), &line); err != nil {
+			t.Fatalf("log line %q is not valid JSON: %v", lines[len(lines)-1], err)
+		}
+		Equal[any](t, expected, line["cold_start"], "cold start attribute does not match")
+	}
+
+	var coldStart map[string]any
+	if err := json.Unmarshal([]byte(strings.SplitN(logs[0], "\n", 2)[0]), &coldStart); err != nil {
+		t.Fatalf("cold start log line is not valid JSON: %v", err)
+	}
+	Equal[any](t, "cold start", coldStart["msg"], "message does not match")
+	for _, phase := range []string{"until_start", "init_hooks", "first_event"} {
+		if _, ok := coldStart[phase+"_seconds"].(float64); !ok {
+			t.Errorf("cold start log line does not contain %s: %v", phase, coldStart)
+		}
+	}
+}
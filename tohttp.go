@@ -0,0 +1,134 @@
+package e5e
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// HTTPHandlerOptions configures the [http.Handler] returned by [ToHTTPHandler].
+type HTTPHandlerOptions struct {
+	// ContextData returns the [Context.Data] for the request, e.g. the API key of the caller.
+	// If it returns an error, the request is rejected with status 400 and the error message.
+	// If nil, the context data is empty.
+	ContextData func(r *http.Request) (any, error)
+
+	// The [Context.Type] of all events. Defaults to "http".
+	TriggerType string
+
+	// The name of the entrypoint in logs, spans and metrics. Defaults to "http".
+	Entrypoint string
+
+	// Requests with a body larger than MaxRequestBytes are rejected with status 413.
+	// If it's zero, 32 MiB are used.
+	MaxRequestBytes int64
+
+	// ErrorHandler writes the response if the handler returned an error.
+	// By default, the response has status 500 and only contains the status text, so internal details
+	// are not sent to clients. Errors are always logged, see [Logger].
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// ToHTTPHandler returns an [http.Handler] that executes h for each HTTP request, so the same handler can be
+// deployed on E5E as well as behind a plain HTTP server.
+//
+// The HTTP request is converted into the request envelope and the result is written as HTTP response
+// the same way as described for [ListenAndServe]. The context data is provided by [HTTPHandlerOptions.ContextData].
+// Each request is executed as an invocation like in the runtime, so [Logger], [RequestID] and [Span] work as well
+// and the metrics are recorded for [HTTPHandlerOptions.Entrypoint].
+//
+// The configuration of the runtime, like E5E_LOG_LEVEL or E5E_TRACE_EXPORTER, is read from the environment
+// when the handler is created, just like [Start] does. It panics if the configuration is invalid.
+//
+//	srv := &http.Server{
+//		Addr: ":8080",
+//		Handler: e5e.ToHTTPHandler[SumData, AuthContext](e5e.HandlerFunc[SumData, AuthContext](Sum), e5e.HTTPHandlerOptions{
+//			ContextData: func(r *http.Request) (any, error) {
+//				return AuthContext{AuthKey: r.Header.Get("X-Auth-Key")}, nil
+//			},
+//		}),
+//	}
+func ToHTTPHandler[T, TContext Data](h Handler[T, TContext], opts HTTPHandlerOptions) http.Handler {
+	return FactoryToHTTPHandler(NewHandlerFactory(h), opts)
+}
+
+// FactoryToHTTPHandler works like [ToHTTPHandler], but takes a [HandlerFactory], e.g. a handler that's wrapped with middleware.
+func FactoryToHTTPHandler(factory HandlerFactory, opts HTTPHandlerOptions) http.Handler {
+	if err := configure(); err != nil {
+		panic(err)
+	}
+	entrypoint := opts.Entrypoint
+	if entrypoint == "" {
+		entrypoint = "http"
+	}
+	return &httpHandler{factory: withInvocation(entrypoint, factory), opts: opts}
+}
+
+// httpHandler executes a handler factory for HTTP requests.
+type httpHandler struct {
+	factory HandlerFactory
+	opts    HTTPHandlerOptions
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	maxBytes := h.opts.MaxRequestBytes
+	if maxBytes == 0 {
+		maxBytes = defaultMaxHTTPRequestBytes
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+	request, err := requestFromHTTP(r)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if h.opts.TriggerType != "" {
+		request.Context.Type = h.opts.TriggerType
+	}
+	if h.opts.ContextData != nil {
+		data, err := h.opts.ContextData(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if request.Context.Data, err = json.Marshal(data); err != nil {
+			logger.Error("marshalling context data failed", "error", err.Error())
+			h.handleError(w, r, fmt.Errorf("marshalling context data: %w", err))
+			return
+		}
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		logger.Error("marshalling request failed", "error", err.Error())
+		h.handleError(w, r, err)
+		return
+	}
+	// Errors of the handler are logged by the invocation.
+	res, err := h.factory.Execute(r.Context(), payload)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	if err := writeHTTPResult(w, res); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "go-e5e: writing result: %v\n", err)
+	}
+}
+
+func (h *httpHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	// The error handler gets the error exactly as it was returned by the handler.
+	if reqErr, ok := err.(*requestError); ok {
+		err = reqErr.err
+	}
+	if h.opts.ErrorHandler != nil {
+		h.opts.ErrorHandler(w, r, err)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
@@ -0,0 +1,161 @@
+package e5e_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestToHTTPHandler(t *testing.T) {
+	t.Parallel()
+
+	handler := e5e.HandlerFunc[IntegrationTestPayload, IntegrationTestContext](
+		func(ctx context.Context, r e5e.Request[IntegrationTestPayload, IntegrationTestContext]) (*e5e.Result, error) {
+			if r.Context.Data.AuthKey != "secret" {
+				return nil, errors.New("unauthorized")
+			}
+			return &e5e.Result{
+				Status:          201,
+				ResponseHeaders: map[string]string{"X-Trigger": r.Context.Type},
+				Data:            r.Data().A + r.Data().B,
+			}, nil
+		})
+	opts := e5e.HTTPHandlerOptions{
+		ContextData: func(r *http.Request) (any, error) {
+			if r.Header.Get("X-Auth-Key") == "" {
+				return nil, errors.New("missing auth key")
+			}
+			return IntegrationTestContext{AuthKey: r.Header.Get("X-Auth-Key")}, nil
+		},
+		TriggerType: "kubernetes",
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+		},
+	}
+
+	tests := []struct {
+		name    string
+		authKey string
+		status  int
+		body    string
+	}{
+		{name: "success", authKey: "secret", status: 201, body: "5"},
+		{name: "context data error", status: 400, body: "missing auth key\n"},
+		{name: "handler error", authKey: "wrong", status: 403, body: "unauthorized\n"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":2,"b":3}`))
+			r.Header.Set("Content-Type", "application/json")
+			if tt.authKey != "" {
+				r.Header.Set("X-Auth-Key", tt.authKey)
+			}
+			w := httptest.NewRecorder()
+			e5e.ToHTTPHandler[IntegrationTestPayload, IntegrationTestContext](handler, opts).ServeHTTP(w, r)
+
+			Equal(t, tt.status, w.Code, "status does not match")
+			Equal(t, tt.body, w.Body.String(), "body does not match")
+			if tt.status == 201 {
+				Equal(t, "kubernetes", w.Header().Get("X-Trigger"), "trigger type does not match")
+				Equal(t, "application/json", w.Header().Get("Content-Type"), "content type does not match")
+			}
+		})
+	}
+}
+
+func TestToHTTPHandlerInvocation(t *testing.T) {
+	handler := e5e.HandlerFunc[any, any](func(ctx context.Context, r e5e.Request[any, any]) (*e5e.Result, error) {
+		e5e.Logger(ctx).Info("handled")
+		return &e5e.Result{Data: e5e.RequestID(ctx)}, nil
+	})
+	h := e5e.ToHTTPHandler[any, any](handler, e5e.HTTPHandlerOptions{Entrypoint: t.Name()})
+
+	stdio := redirectStdio(t, "")
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Request-Id", "test-request-id")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	_, stderr := stdio.ReadAndRestore()
+
+	Equal(t, `"test-request-id"`, w.Body.String(), "request ID does not match")
+	Equal(t, "test-request-id", w.Header().Get("X-Request-Id"), "request ID was not echoed")
+	Equal(t, true, strings.Contains(stderr, `"msg":"handled","entrypoint":"`+t.Name()+`"`), "log line was not written: "+stderr)
+	Equal(t, true, strings.Contains(stderr, `"request_id":"test-request-id"`), "request ID was not logged: "+stderr)
+}
+
+func TestToHTTPHandlerDefaultErrorHandler(t *testing.T) {
+	handler := e5e.HandlerFunc[any, any](func(ctx context.Context, r e5e.Request[any, any]) (*e5e.Result, error) {
+		return nil, errors.New("connecting to 10.0.0.1 failed")
+	})
+
+	stdio := redirectStdio(t, "")
+	w := httptest.NewRecorder()
+	e5e.ToHTTPHandler[any, any](handler, e5e.HTTPHandlerOptions{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	_, stderr := stdio.ReadAndRestore()
+
+	Equal(t, http.StatusInternalServerError, w.Code, "status does not match")
+	Equal(t, "Internal Server Error\n", w.Body.String(), "body does not match")
+	Equal(t, true, strings.Contains(stderr, `"error":"connecting to 10.0.0.1 failed"`), "error was not logged: "+stderr)
+}
+
+func TestToHTTPHandlerMaxRequestBytes(t *testing.T) {
+	t.Parallel()
+
+	handler := e5e.HandlerFunc[any, any](func(ctx context.Context, r e5e.Request[any, any]) (*e5e.Result, error) {
+		return &e5e.Result{Status: 204}, nil
+	})
+	h := e5e.ToHTTPHandler[any, any](handler, e5e.HTTPHandlerOptions{MaxRequestBytes: 16})
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	fw, _ := mw.CreateFormFile("upload", "large.txt")
+	_, _ = fw.Write([]byte(strings.Repeat("x", 64)))
+	_ = mw.Close()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{name: "small body", contentType: "text/plain", body: "small", status: 204},
+		{name: "large body", contentType: "text/plain", body: strings.Repeat("x", 64), status: 413},
+		{name: "large file", contentType: mw.FormDataContentType(), body: form.String(), status: 413},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", tt.contentType)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		Equal(t, tt.status, w.Code, "status of "+tt.name+" does not match")
+	}
+}
+
+func TestToHTTPHandlerConfiguration(t *testing.T) {
+	t.Setenv("E5E_LOG_LEVEL", "debug")
+	handler := e5e.HandlerFunc[any, any](func(ctx context.Context, r e5e.Request[any, any]) (*e5e.Result, error) {
+		e5e.Logger(ctx).Debug("debugging")
+		return &e5e.Result{Status: 204}, nil
+	})
+	h := e5e.ToHTTPHandler[any, any](handler, e5e.HTTPHandlerOptions{})
+
+	stdio := redirectStdio(t, "")
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	_, stderr := stdio.ReadAndRestore()
+	Equal(t, true, strings.Contains(stderr, `"msg":"debugging"`), "log level was not applied: "+stderr)
+
+	t.Setenv("E5E_LOG_LEVEL", "invalid")
+	defer func() {
+		Equal(t, true, recover() != nil, "invalid configuration did not panic")
+	}()
+	e5e.ToHTTPHandler[any, any](handler, e5e.HTTPHandlerOptions{})
+}
@@ -0,0 +1,420 @@
+package e5e
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables that control the export of spans, see [SetSpanExporter].
+const (
+	envTraceExporter = "E5E_TRACE_EXPORTER"
+	envOTLPEndpoint  = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	envOTLPTraces    = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	envOTLPHeaders   = "OTEL_EXPORTER_OTLP_HEADERS"
+	envServiceName   = "OTEL_SERVICE_NAME"
+)
+
+// spanExportTimeout is the maximum duration the export of a batch of spans may take.
+const spanExportTimeout = 5 * time.Second
+
+// maxPendingSpans is the maximum number of finished spans waiting for their export.
+// Further spans are dropped until the exporter catches up.
+const maxPendingSpans = 2048
+
+// TraceID identifies a trace, as defined by the W3C Trace Context specification.
+type TraceID [16]byte
+
+// String returns the lowercase hex encoding of the ID.
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+// IsValid returns true if the ID is not all zeros.
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+// SpanID identifies a span within a trace, as defined by the W3C Trace Context specification.
+type SpanID [8]byte
+
+// String returns the lowercase hex encoding of the ID.
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// IsValid returns true if the ID is not all zeros.
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// SpanContext contains the identifiers of a span that are propagated to other services.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+}
+
+// IsValid returns true if both the trace ID and the span ID are valid.
+func (c SpanContext) IsValid() bool { return c.TraceID.IsValid() && c.SpanID.IsValid() }
+
+// TraceParent returns the value of the "traceparent" header for this span context.
+func (c SpanContext) TraceParent() string {
+	flags := "00"
+	if c.Sampled {
+		flags = "01"
+	}
+	return "00-" + c.TraceID.String() + "-" + c.SpanID.String() + "-" + flags
+}
+
+// parseTraceParent parses the value of a "traceparent" header.
+func parseTraceParent(value string) (SpanContext, bool) {
+	var c SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return c, false
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return c, false
+	}
+	if _, err := hex.Decode(c.TraceID[:], []byte(parts[1])); err != nil {
+		return c, false
+	}
+	if _, err := hex.Decode(c.SpanID[:], []byte(parts[2])); err != nil {
+		return c, false
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return c, false
+	}
+	c.Sampled = flags[0]&1 == 1
+	return c, c.IsValid()
+}
+
+// SpanKind describes the relationship of a span to its parent and children, using the values of OpenTelemetry.
+type SpanKind int
+
+const (
+	// SpanKindInternal is an operation inside of the function.
+	SpanKindInternal SpanKind = 1
+	// SpanKindServer is the handling of an incoming request, i.e. an invocation of the function.
+	SpanKindServer SpanKind = 2
+	// SpanKindClient is an outgoing request.
+	SpanKindClient SpanKind = 3
+)
+
+// SpanData is the immutable record of a finished span, as passed to a [SpanExporter].
+type SpanData struct {
+	Name         string
+	Kind         SpanKind
+	SpanContext  SpanContext
+	ParentSpanID SpanID
+	StartTime    time.Time
+	EndTime      time.Time
+	Attributes   map[string]any
+
+	// The error message, if the operation failed.
+	Error string
+}
+
+// SpanExporter sends finished spans to a tracing backend.
+type SpanExporter interface {
+	// ExportSpans exports the given spans. It's called in the background with the spans finished in the meantime,
+	// and a last time when [Start] returns. Calls never overlap.
+	ExportSpans(ctx context.Context, spans []SpanData) error
+}
+
+// Span is a single operation within a trace. All methods are safe to be called on a nil span.
+//
+// Each invocation has a span of the kind [SpanKindServer], which continues the trace given by
+// the "traceparent" and "tracestate" request headers. Further spans can be created with [StartSpan].
+type Span struct {
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanContext returns the identifiers of the span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.SpanContext
+}
+
+// SetAttribute sets an attribute of the span.
+// The value should be a string, a bool, an integer or a floating point number.
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes[key] = value
+}
+
+// SetError marks the operation of the span as failed.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Error = err.Error()
+}
+
+// End finishes the span. It will be exported at the end of the invocation, if the trace is sampled.
+// Calling End more than once has no effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now()
+	if s.data.SpanContext.Sampled {
+		data := s.data
+		data.Attributes = make(map[string]any, len(s.data.Attributes))
+		for k, v := range s.data.Attributes {
+			data.Attributes[k] = v
+		}
+		tracer.add(data)
+	}
+}
+
+// spanContextKey is the context key of the current span.
+type spanContextKey struct{}
+
+// SpanFromContext returns the current span, or nil if there is none.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanContextKey{}).(*Span)
+	return s
+}
+
+// StartSpan starts a span as child of the current span of the context.
+// The returned context contains the new span, which must be ended by calling [Span.End].
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	return startSpan(ctx, name, SpanKindInternal, SpanFromContext(ctx).SpanContext())
+}
+
+// startSpan starts a span with the given parent. If the parent is invalid, a new trace is started.
+func startSpan(ctx context.Context, name string, kind SpanKind, parent SpanContext) (context.Context, *Span) {
+	s := &Span{data: SpanData{
+		Name:       name,
+		Kind:       kind,
+		StartTime:  time.Now(),
+		Attributes: make(map[string]any),
+	}}
+	if parent.IsValid() {
+		s.data.SpanContext = parent
+		s.data.ParentSpanID = parent.SpanID
+	} else {
+		_, _ = rand.Read(s.data.SpanContext.TraceID[:])
+		s.data.SpanContext.Sampled = true
+	}
+	_, _ = rand.Read(s.data.SpanContext.SpanID[:])
+	if !tracer.enabled() {
+		s.data.SpanContext.Sampled = parent.IsValid() && parent.Sampled
+	}
+	return context.WithValue(ctx, spanContextKey{}, s), s
+}
+
+// tracer collects the finished spans and exports them.
+var tracer = &tracerState{}
+
+type tracerState struct {
+	mu          sync.Mutex
+	exporter    SpanExporter
+	exporterSet bool
+	pending     []SpanData
+	dropped     int
+
+	// wake signals the running export goroutine that spans are pending. It's nil if none is running.
+	wake chan struct{}
+	// done is closed once the export goroutine returned.
+	done chan struct{}
+}
+
+// SetSpanExporter sets the exporter of all spans. If it's nil, spans are not exported.
+// It takes precedence over the configuration by the environment:
+//
+//   - E5E_TRACE_EXPORTER selects the exporter. "otlp" exports the spans using [NewOTLPExporter],
+//     "stderr" writes them to [os.Stderr] using [NewStderrExporter].
+//   - OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT set the endpoint of the OTLP exporter,
+//     OTEL_EXPORTER_OTLP_HEADERS contains additional headers like "key1=value1,key2=value2".
+//   - OTEL_SERVICE_NAME sets the service name of the spans. It defaults to the name of the binary.
+func SetSpanExporter(exporter SpanExporter) {
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+	tracer.exporter, tracer.exporterSet = exporter, true
+}
+
+// configureTracing sets the span exporter from the environment, unless it was set by [SetSpanExporter].
+func configureTracing() error {
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+	if tracer.exporterSet {
+		return nil
+	}
+
+	switch name := os.Getenv(envTraceExporter); name {
+	case "", "none":
+		tracer.exporter = nil
+	case "stderr":
+		tracer.exporter = NewStderrExporter()
+	case "otlp":
+		endpoint := os.Getenv(envOTLPTraces)
+		if endpoint == "" {
+			endpoint = strings.TrimSuffix(os.Getenv(envOTLPEndpoint), "/")
+			if endpoint == "" {
+				endpoint = "http://localhost:4318"
+			}
+			endpoint += "/v1/traces"
+		}
+		exporter := NewOTLPExporter(endpoint)
+		for _, h := range splitList(os.Getenv(envOTLPHeaders)) {
+			k, v, _ := strings.Cut(h, "=")
+			exporter.Headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+		tracer.exporter = exporter
+	default:
+		return fmt.Errorf("go-e5e: unknown %s %q", envTraceExporter, name)
+	}
+	return nil
+}
+
+func (t *tracerState) enabled() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.exporter != nil
+}
+
+// add queues the finished span for the export in the background.
+// The export goroutine is started with the first span and stopped by [runShutdownHooks], which exports the rest.
+func (t *tracerState) add(span SpanData) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.exporter == nil {
+		return
+	}
+	if len(t.pending) >= maxPendingSpans {
+		t.dropped++
+		return
+	}
+	t.pending = append(t.pending, span)
+
+	if t.wake == nil {
+		t.wake, t.done = make(chan struct{}, 1), make(chan struct{})
+		go t.run(t.wake, t.done)
+		OnShutdown(t.shutdown)
+	}
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run exports the pending spans whenever it's woken up, until wake is closed.
+func (t *tracerState) run(wake <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	for range wake {
+		t.export()
+	}
+}
+
+// shutdown stops the export goroutine and exports the remaining spans.
+func (t *tracerState) shutdown(context.Context) error {
+	t.mu.Lock()
+	wake, done := t.wake, t.done
+	t.wake, t.done = nil, nil
+	t.mu.Unlock()
+	if wake != nil {
+		close(wake)
+		<-done
+	}
+	t.export()
+	return nil
+}
+
+// export exports all pending spans. Failures are logged.
+func (t *tracerState) export() {
+	t.mu.Lock()
+	exporter, spans, dropped := t.exporter, t.pending, t.dropped
+	t.pending, t.dropped = nil, 0
+	t.mu.Unlock()
+	if dropped > 0 {
+		logger.Warn("spans were dropped, since the exporter is too slow", "count", dropped)
+	}
+	if exporter == nil || len(spans) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), spanExportTimeout)
+	defer cancel()
+	if err := exporter.ExportSpans(ctx, spans); err != nil {
+		logger.Warn("exporting spans failed", "error", err.Error())
+	}
+}
+
+// serviceName returns the service name of all spans.
+func serviceName() string {
+	if name := os.Getenv(envServiceName); name != "" {
+		return name
+	}
+	name := os.Args[0]
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// NewTracingTransport returns an [http.RoundTripper] that propagates the trace of the request context
+// to outgoing requests, using the "traceparent" and "tracestate" headers. Each request is recorded
+// as span of the kind [SpanKindClient]. If base is nil, [http.DefaultTransport] is used.
+//
+//	client := &http.Client{Transport: e5e.NewTracingTransport(nil)}
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com", nil)
+//	resp, err := client.Do(req)
+func NewTracingTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return tracingTransport{base: base}
+}
+
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+func (t tracingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	parent := SpanFromContext(r.Context())
+	if parent == nil {
+		return t.base.RoundTrip(r)
+	}
+
+	_, span := startSpan(r.Context(), "HTTP "+r.Method, SpanKindClient, parent.SpanContext())
+	defer span.End()
+	span.SetAttribute("http.request.method", r.Method)
+	span.SetAttribute("url.full", r.URL.Redacted())
+
+	// The request must not be modified by a RoundTripper.
+	r = r.Clone(r.Context())
+	r.Header.Set("traceparent", span.SpanContext().TraceParent())
+	if state := span.SpanContext().TraceState; state != "" {
+		r.Header.Set("tracestate", state)
+	}
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		span.SetError(err)
+		return resp, err
+	}
+	span.SetAttribute("http.response.status_code", resp.StatusCode)
+	if resp.StatusCode >= 500 {
+		span.SetError(fmt.Errorf("status %d", resp.StatusCode))
+	}
+	return resp, nil
+}
@@ -0,0 +1,179 @@
+package e5e_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go.anx.io/e5e/v2"
+)
+
+func TestTracing(t *testing.T) {
+	type span struct {
+		TraceID      string `json:"traceId"`
+		SpanID       string `json:"spanId"`
+		ParentSpanID string `json:"parentSpanId"`
+		TraceState   string `json:"traceState"`
+		Name         string `json:"name"`
+		Kind         int    `json:"kind"`
+		Attributes   []struct {
+			Key   string         `json:"key"`
+			Value map[string]any `json:"value"`
+		} `json:"attributes"`
+	}
+	var (
+		mu    sync.Mutex
+		spans = make(map[string]span)
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []span `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					spans[s.Name] = s
+				}
+			}
+		}
+	}))
+	defer collector.Close()
+
+	var downstreamTraceParent string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstreamTraceParent = r.Header.Get("traceparent")
+	}))
+	defer downstream.Close()
+
+	e5e.SetSpanExporter(e5e.NewOTLPExporter(collector.URL + "/v1/traces"))
+	defer e5e.SetSpanExporter(nil)
+
+	entrypoint := t.Name()
+	e5e.AddHandlerFunc(entrypoint, func(ctx context.Context, r e5e.Request[IntegrationTestPayload, IntegrationTestContext]) (*e5e.Result, error) {
+		client := &http.Client{Transport: e5e.NewTracingTransport(nil)}
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, downstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		return &e5e.Result{Status: 201, Data: r.Data().A + r.Data().B}, nil
+	})
+
+	var payload map[string]any
+	_ = json.Unmarshal(defaultPayload, &payload)
+	payload["event"].(map[string]any)["request_headers"] = map[string]string{
+		"Traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"Tracestate":  "vendor=value",
+	}
+	encoded, _ := json.Marshal(payload)
+
+	stdio := redirectStdio(t, string(encoded))
+	os.Args = buildOptions(entrypoint)
+	e5e.Start(context.Background())
+	stdout, _ := stdio.ReadAndRestore()
+	Equal(t, stdoutTerminationSequence+`{"result":{"status":201,"response_headers":{"X-Request-Id":"0af7651916cd43dd8448eb211c80319c"},"data":5}}`, stdout, "stdout does not match")
+
+	mu.Lock()
+	defer mu.Unlock()
+	server, ok := spans[entrypoint]
+	if !ok {
+		t.Fatalf("invocation span was not exported: %+v", spans)
+	}
+	Equal(t, "0af7651916cd43dd8448eb211c80319c", server.TraceID, "trace ID does not match")
+	Equal(t, "b7ad6b7169203331", server.ParentSpanID, "parent span ID does not match")
+	Equal(t, "vendor=value", server.TraceState, "trace state does not match")
+	Equal(t, 2, server.Kind, "kind does not match")
+	attributes := make(map[string]any)
+	for _, a := range server.Attributes {
+		for _, v := range a.Value {
+			attributes[a.Key] = v
+		}
+	}
+	Equal[any](t, "201", attributes["e5e.result.status"], "status attribute does not match")
+	Equal[any](t, "object", attributes["e5e.event.type"], "event type attribute does not match")
+	Equal[any](t, entrypoint, attributes["e5e.entrypoint"], "entrypoint attribute does not match")
+
+	client, ok := spans["HTTP GET"]
+	if !ok {
+		t.Fatalf("client span was not exported: %+v", spans)
+	}
+	Equal(t, server.TraceID, client.TraceID, "client trace ID does not match")
+	Equal(t, server.SpanID, client.ParentSpanID, "client parent span ID does not match")
+	Equal(t, "00-"+client.TraceID+"-"+client.SpanID+"-01", downstreamTraceParent, "propagated traceparent does not match")
+}
+
+// blockingExporter blocks the first export until it's released.
+type blockingExporter struct {
+	release chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+	spans   []e5e.SpanData
+}
+
+func (e *blockingExporter) ExportSpans(_ context.Context, spans []e5e.SpanData) error {
+	e.once.Do(func() { <-e.release })
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func TestTracingSlowExporter(t *testing.T) {
+	exporter := &blockingExporter{release: make(chan struct{})}
+	e5e.SetSpanExporter(exporter)
+	defer e5e.SetSpanExporter(nil)
+
+	entrypoint := t.Name()
+	e5e.AddHandlerFunc(entrypoint, func(ctx context.Context, r e5e.Request[any, any]) (*e5e.Result, error) {
+		return nil, nil
+	})
+
+	factory := e5e.Invocation(entrypoint, e5e.HandlerFactoryFunc(func(ctx context.Context, payload []byte) (*e5e.Result, error) {
+		return nil, nil
+	}))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2; i++ {
+			_, _ = factory.Execute(context.Background(), defaultPayload)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("invocations wait for the export of the spans")
+	}
+
+	// The remaining spans are exported when Start returns.
+	close(exporter.release)
+	stdio := redirectStdio(t, string(defaultPayload))
+	os.Args = buildOptions(entrypoint)
+	e5e.Start(context.Background())
+	_, _ = stdio.ReadAndRestore()
+
+	exporter.mu.Lock()
+	defer exporter.mu.Unlock()
+	Equal(t, 3, len(exporter.spans), "number of exported spans does not match")
+}